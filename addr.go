@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// nodeAddr is the canonical form of a node address and is used as the theList key.
+// The ip is always held in its 16 byte form so an ipv4 address and the same address
+// mapped into ipv6 are equal and can not be added twice
+type nodeAddr struct {
+	ip   [net.IPv6len]byte
+	port uint16
+}
+
+// newNodeAddr returns the canonical address for an ip and port
+func newNodeAddr(ip net.IP, port uint16) nodeAddr {
+	var a nodeAddr
+	copy(a.ip[:], ip.To16())
+	a.port = port
+	return a
+}
+
+// naAddr returns the canonical address for a network address
+func naAddr(na *wire.NetAddress) nodeAddr {
+	return newNodeAddr(na.IP, na.Port)
+}
+
+// parseNodeAddr parses an ip:port or [ipv6]:port string into a canonical address
+func parseNodeAddr(s string) (nodeAddr, error) {
+	host, p, err := net.SplitHostPort(s)
+	if err != nil {
+		return nodeAddr{}, err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nodeAddr{}, fmt.Errorf("Invalid ip address %s", host)
+	}
+	port, err := strconv.ParseUint(p, 10, 16)
+	if err != nil {
+		return nodeAddr{}, fmt.Errorf("Invalid port %s", p)
+	}
+	return newNodeAddr(ip, uint16(port)), nil
+}
+
+// IP returns the ip address with ipv4 addresses in their 4 byte form
+func (a nodeAddr) IP() net.IP {
+	ip := net.IP(append([]byte(nil), a.ip[:]...))
+	if x := ip.To4(); x != nil {
+		return x
+	}
+	return ip
+}
+
+// Port returns the port number
+func (a nodeAddr) Port() uint16 {
+	return a.port
+}
+
+// is4 returns true for an ipv4 address
+func (a nodeAddr) is4() bool {
+	return net.IP(a.ip[:]).To4() != nil
+}
+
+// String returns the address as ip:port or [ipv6]:port which is used in the logs,
+// the web interface and to connect to the node
+func (a nodeAddr) String() string {
+	return net.JoinHostPort(a.IP().String(), strconv.Itoa(int(a.port)))
+}
+
+/*
+
+ */
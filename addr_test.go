@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// mustAddr returns the canonical address for an ip:port string used in the tests
+func mustAddr(s string) nodeAddr {
+	a, err := parseNodeAddr(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func TestNodeAddr(t *testing.T) {
+
+	var tests = []struct {
+		ip   net.IP
+		port uint16
+		str  string
+	}{
+		{net.IPv4(1, 2, 3, 4), 8333, "1.2.3.4:8333"},
+		{net.ParseIP("1.2.3.4").To4(), 8333, "1.2.3.4:8333"},
+		{net.ParseIP("::ffff:1.2.3.4"), 8333, "1.2.3.4:8333"},
+		{net.ParseIP("2a01:4f8::1"), 18333, "[2a01:4f8::1]:18333"},
+	}
+
+	for _, tt := range tests {
+		a := newNodeAddr(tt.ip, tt.port)
+		if a.String() != tt.str {
+			t.Errorf("ip: %s string: %s expected: %s", tt.ip, a, tt.str)
+		}
+		if p, err := parseNodeAddr(tt.str); err != nil || p != a {
+			t.Errorf("parse %s: %v %v", tt.str, p, err)
+		}
+		if a.is4() != (tt.ip.To4() != nil) {
+			t.Errorf("ip: %s is4: %v", tt.ip, a.is4())
+		}
+	}
+	if len(newNodeAddr(net.IPv4(1, 2, 3, 4), 8333).IP()) != net.IPv4len {
+		t.Errorf("ipv4 address not returned in 4 byte form")
+	}
+
+	for _, bad := range []string{"1.2.3.4", "1.2.3:8333", "1.2.3.4:99999", "[::1]:port"} {
+		if _, err := parseNodeAddr(bad); err == nil {
+			t.Errorf("parsed invalid address %s", bad)
+		}
+	}
+}
+
+func TestMappedDuplicate(t *testing.T) {
+
+	s := &dnsseeder{port: 8333, maxSize: 1250}
+	s.theList = make(map[nodeAddr]*node)
+
+	// the same node reported in plain and ipv4 mapped form is only added once
+	for _, ip := range []net.IP{net.ParseIP("1.2.3.4").To4(), net.ParseIP("::ffff:1.2.3.4")} {
+		for _, port := range []uint16{8333, 18333} {
+			s.addNode(wire.NewNetAddressIPPort(ip, port, 0), "")
+		}
+	}
+	if len(s.theList) != 2 {
+		t.Errorf("theList has %v nodes expected: 2", len(s.theList))
+	}
+	for k, nd := range s.theList {
+		if len(nd.na.IP) != net.IPv4len || nd.addr != k {
+			t.Errorf("node %s stored as %s", k, nd.na.IP)
+		}
+	}
+}
+
+/*
+
+ */
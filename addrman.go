@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"log"
+	"strconv"
+)
+
+// The node list is split into new and tried tables in the same way as the address
+// manager in Bitcoin Core. An address is placed in a new bucket chosen from its source
+// netgroup and its own netgroup, so the addresses one peer or subnet can report only
+// ever compete for a small part of the table. The buckets alone would let one source
+// hold 4096 addresses so the nodes from each source group are also capped at an
+// eighth of maxSize. A node moves to the tried table once we
+// have crawled it successfully and its tried bucket depends only on its own netgroup.
+// Bucket positions are hashed with a secret so they can not be predicted by an attacker.
+const (
+	newBucketCount           = 1024 // number of buckets in the new table
+	triedBucketCount         = 256  // number of buckets in the tried table
+	bucketSize               = 64   // number of slots in each bucket
+	newBucketsPerSourceGroup = 64   // new buckets the addresses from one source group can use
+	triedBucketsPerGroup     = 8    // tried buckets the nodes from one netgroup can use
+	sourceShare              = 8    // one source group can hold 1/sourceShare of maxSize nodes
+	minPerSource             = 8    // nodes one source group can always hold however small maxSize is
+)
+
+const (
+	// table a node is stored in
+	tableNone  = iota // not in a table
+	tableNew          // reported to us but not crawled successfully yet
+	tableTried        // crawled successfully at least once
+)
+
+const (
+	srcSeeder  = "seeder:" // prefix for the source group of addresses from the other seeders
+	srcInitial = "initial" // source group for the InitialIPs
+)
+
+// addrTables holds the new and tried tables for a seeder. Buckets are only
+// allocated when first used
+type addrTables struct {
+	key    [32]byte // secret used to choose buckets and slots
+	new    [newBucketCount][]*node
+	tried  [triedBucketCount][]*node
+	nNew   int // nodes in the new table
+	nTried int // nodes in the tried table
+}
+
+// newSecret returns a random secret for the bucket positions of one seeder
+func newSecret() [32]byte {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		log.Printf("warning - unable to create random bucket secret - %v\n", err)
+	}
+	return key
+}
+
+// hash returns a keyed hash of the parts
+func (t *addrTables) hash(parts ...string) uint64 {
+	h := sha256.New()
+	h.Write(t.key[:])
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return binary.LittleEndian.Uint64(h.Sum(nil)[:8])
+}
+
+// newPos returns the new table bucket and slot for an address from a source group
+func (t *addrTables) newPos(k, group, src string) (int, int) {
+	h1 := t.hash(group, src) % newBucketsPerSourceGroup
+	b := int(t.hash(src, strconv.FormatUint(h1, 10)) % newBucketCount)
+	return b, int(t.hash("N", strconv.Itoa(b), k) % bucketSize)
+}
+
+// triedPos returns the tried table bucket and slot for an address
+func (t *addrTables) triedPos(k, group string) (int, int) {
+	h1 := t.hash(k) % triedBucketsPerGroup
+	b := int(t.hash(group, strconv.FormatUint(h1, 10)) % triedBucketCount)
+	return b, int(t.hash("K", strconv.Itoa(b), k) % bucketSize)
+}
+
+// bucketFor returns the bucket slice for a table allocating it if needed
+func (t *addrTables) bucketFor(table uint32, b int) []*node {
+	buckets := t.new[:]
+	if table == tableTried {
+		buckets = t.tried[:]
+	}
+	if buckets[b] == nil {
+		buckets[b] = make([]*node, bucketSize)
+	}
+	return buckets[b]
+}
+
+// maxPerSource returns the most nodes theList can hold that were reported by one source group
+func (s *dnsseeder) maxPerSource() int {
+	if n := s.maxSize / sourceShare; n > minPerSource {
+		return n
+	}
+	return minPerSource
+}
+
+// newSlotHolder returns the node holding the new table slot for a node or nil if the
+// slot is free. It returns false if the slot holds a node that must not be replaced
+func (s *dnsseeder) newSlotHolder(nd *node) (*node, bool) {
+
+	b, slot := s.tables.newPos(nd.addr.String(), nd.group, nd.srcGroup)
+	old := s.tables.bucketFor(tableNew, b)[slot]
+	if old == nil || old == nd {
+		return nil, true
+	}
+	return old, evictable(old)
+}
+
+// placeNew puts a node that is not in a table into its new table slot. If the slot is
+// taken by a node that is worth less than an untried address then that node is removed,
+// otherwise the slot is kept and false is returned
+func (s *dnsseeder) placeNew(nd *node) bool {
+
+	b, slot := s.tables.newPos(nd.addr.String(), nd.group, nd.srcGroup)
+	bucket := s.tables.bucketFor(tableNew, b)
+
+	if old := bucket[slot]; old != nil && old != nd {
+		if evictable(old) == false {
+			return false
+		}
+		if config.debug {
+			log.Printf("%s - debug - node %s replaces %s in new bucket %v\n", s.name, nd.addr, old.addr, b)
+		}
+		s.removeNode(old.addr)
+	}
+
+	bucket[slot] = nd
+	nd.table, nd.bucket, nd.slot = tableNew, b, slot
+	s.tables.nNew++
+	return true
+}
+
+// makeTried moves a node we have crawled successfully to the tried table. If its tried
+// slot holds another good node the new node stays in the new table, otherwise the old
+// node is moved back to the new table so it gets tried again
+func (s *dnsseeder) makeTried(nd *node) {
+
+	if nd.table == tableTried {
+		return
+	}
+
+	b, slot := s.tables.triedPos(nd.addr.String(), nd.group)
+	bucket := s.tables.bucketFor(tableTried, b)
+
+	if old := bucket[slot]; old != nil {
+		if old.status == statusCG {
+			return
+		}
+		s.clearSlot(old)
+		if s.placeNew(old) == false {
+			s.removeNode(old.addr)
+		}
+	}
+
+	s.clearSlot(nd)
+	bucket[slot] = nd
+	nd.table, nd.bucket, nd.slot = tableTried, b, slot
+	s.tables.nTried++
+}
+
+// restoreSlot puts a node loaded from a snapshot back in a table. A good node goes back
+// to the tried table and the others to the new table. Nodes already loaded are never
+// replaced so a node whose slot is taken stays out of the tables until it is tried again
+func (s *dnsseeder) restoreSlot(nd *node) {
+
+	if nd.status == statusCG {
+		b, slot := s.tables.triedPos(nd.addr.String(), nd.group)
+		if bucket := s.tables.bucketFor(tableTried, b); bucket[slot] == nil {
+			bucket[slot] = nd
+			nd.table, nd.bucket, nd.slot = tableTried, b, slot
+			s.tables.nTried++
+			return
+		}
+	}
+
+	b, slot := s.tables.newPos(nd.addr.String(), nd.group, nd.srcGroup)
+	if bucket := s.tables.bucketFor(tableNew, b); bucket[slot] == nil {
+		bucket[slot] = nd
+		nd.table, nd.bucket, nd.slot = tableNew, b, slot
+		s.tables.nNew++
+	}
+}
+
+// clearSlot removes a node from its table slot
+func (s *dnsseeder) clearSlot(nd *node) {
+	switch nd.table {
+	case tableNew:
+		s.tables.new[nd.bucket][nd.slot] = nil
+		s.tables.nNew--
+	case tableTried:
+		s.tables.tried[nd.bucket][nd.slot] = nil
+		s.tables.nTried--
+	}
+	nd.table = tableNone
+}
+
+// table2str will return the string description of the table a node is in
+func table2str(table uint32) string {
+	switch table {
+	case tableNone:
+		return "none"
+	case tableNew:
+		return "new"
+	case tableTried:
+		return "tried"
+	default:
+		return "Unknown"
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/gombadi/dnsseeder/simpeer"
+	"github.com/miekg/dns"
+)
+
+func TestNewTableSourceLimit(t *testing.T) {
+
+	s := &dnsseeder{port: 8333, maxSize: 100000}
+	s.theList = make(map[nodeAddr]*node)
+
+	// one source reporting addresses from many netgroups can only use its own buckets
+	for i := 0; i < 10000; i++ {
+		ip := net.IPv4(byte(1+i%200), byte(i/200), 1, 1)
+		s.addNode(wire.NewNetAddressIPPort(ip, 8333, 0), "6.6.0.0/16")
+	}
+	buckets := make(map[int]bool)
+	for _, nd := range s.theList {
+		if nd.table != tableNew {
+			t.Fatalf("node %s is in the %s table expected: new", nd.addr, table2str(nd.table))
+		}
+		buckets[nd.bucket] = true
+	}
+	if len(buckets) > newBucketsPerSourceGroup {
+		t.Errorf("one source used %v new buckets max: %v", len(buckets), newBucketsPerSourceGroup)
+	}
+	if max := newBucketsPerSourceGroup * bucketSize; len(s.theList) > max {
+		t.Errorf("one source added %v addresses max: %v", len(s.theList), max)
+	}
+	if s.tables.nNew != len(s.theList) {
+		t.Errorf("new table count: %v expected: %v", s.tables.nNew, len(s.theList))
+	}
+
+	// a different source still gets its addresses in
+	flooded := len(s.theList)
+	for i := 0; i < 20; i++ {
+		s.addNode(wire.NewNetAddressIPPort(net.IPv4(211, byte(i), 1, 1), 8333, 0), "7.7.0.0/16")
+	}
+	if added := len(s.theList) - flooded; added < 15 {
+		t.Errorf("second source added %v of 20 addresses", added)
+	}
+}
+
+func TestSourceCap(t *testing.T) {
+
+	s := &dnsseeder{port: 8333, maxSize: defMaxSize}
+	s.theList = make(map[nodeAddr]*node)
+
+	// one source reporting addresses from many netgroups can only fill an eighth of theList
+	for i := 0; i < defMaxSize; i++ {
+		s.addNode(wire.NewNetAddressIPPort(net.IPv4(byte(1+i%200), byte(i/200), 1, 1), 8333, 0), "6.6.0.0/16")
+	}
+	if max := defMaxSize / sourceShare; len(s.theList) != max || s.srcCounts["6.6.0.0/16"] != max {
+		t.Errorf("one source added %v addresses count: %v expected: %v", len(s.theList), s.srcCounts["6.6.0.0/16"], max)
+	}
+
+	// removing a node makes room for another address from the same source
+	for k := range s.theList {
+		s.removeNode(k)
+		break
+	}
+	if s.addNode(wire.NewNetAddressIPPort(net.IPv4(201, 1, 1, 1), 8333, 0), "6.6.0.0/16") == nil {
+		t.Errorf("address refused after a node from its source was removed")
+	}
+
+	// a small list still takes a few addresses from each source
+	s = &dnsseeder{port: 8333, maxSize: 10}
+	s.theList = make(map[nodeAddr]*node)
+	if s.maxPerSource() != minPerSource {
+		t.Errorf("max per source: %v expected: %v", s.maxPerSource(), minPerSource)
+	}
+}
+
+func TestRefusedKeepsSlot(t *testing.T) {
+
+	s := &dnsseeder{port: 8333, maxSize: 10, evictPolicy: evictRefuse}
+	s.theList = make(map[nodeAddr]*node)
+
+	// fill theList with nodes that could be replaced in their new table slot
+	for i := 0; len(s.theList) <= s.maxSize; i++ {
+		nd := s.addNode(wire.NewNetAddressIPPort(net.IPv4(byte(20+i), 1, 1, 1), 8333, 0), fmt.Sprintf("%d.0.0.0/16", i%2+6))
+		if nd != nil {
+			nd.status = statusNG
+		}
+	}
+
+	// find an address whose new table slot is held by one of them
+	var na *wire.NetAddress
+	var holder *node
+	for i := 0; i < 65536 && holder == nil; i++ {
+		na = wire.NewNetAddressIPPort(net.IPv4(100, byte(i/256), byte(i%256), 1), 8333, 0)
+		holder, _ = s.newSlotHolder(&node{addr: naAddr(na), group: netGroup(na.IP), srcGroup: "6.0.0.0/16"})
+	}
+	if holder == nil {
+		t.Fatalf("no address found that shares a new table slot")
+	}
+
+	// a reload lowered MaxSize so even replacing the node in the slot leaves the list
+	// too big. The address is refused and the node holding its slot stays
+	s.maxSize = 5
+	size := len(s.theList)
+	if s.addNode(na, "6.0.0.0/16") != nil {
+		t.Fatalf("address added to a full list with the refuse policy")
+	}
+	if len(s.theList) != size || s.theList[holder.addr] != holder {
+		t.Errorf("refused address removed node %s. List size: %v expected: %v", holder.addr, len(s.theList), size)
+	}
+	if s.tables.bucketFor(tableNew, holder.bucket)[holder.slot] != holder {
+		t.Errorf("new table slot of %s is no longer held by it", holder.addr)
+	}
+}
+
+func TestSourceShare(t *testing.T) {
+
+	s := newSimSeeder(t, simpeer.NewNetwork(), simNetwork())
+	s.maxDNSPerGrp = 0
+	for i := 1; i <= 30; i++ {
+		s.addNa(simAddr(fmt.Sprintf("20.%d.0.1", i), 8333), "6.6.0.0/16")
+	}
+	for i := 1; i <= dnsPerSource; i++ {
+		s.addNa(simAddr(fmt.Sprintf("30.%d.0.1", i), 8333), "7.7.0.0/16")
+	}
+
+	// one source can only take its share of the crawls started in one run
+	rc := make(chan *result)
+	s.startCrawlers(rc)
+	started := make(map[string]int)
+	for _, nd := range s.theList {
+		if nd.crawlActive {
+			started[nd.srcGroup]++
+		}
+	}
+	if perSrc := int(s.crawlsPerSource()); started["6.6.0.0/16"] != perSrc || started["7.7.0.0/16"] != dnsPerSource {
+		t.Errorf("crawls started per source: %v expected %v and %v", started, perSrc, dnsPerSource)
+	}
+	for n, i := s.activeCrawls, 0; i < n; i++ {
+		s.processResult(<-rc)
+	}
+
+	// the dns answer takes its share from each source before using more from one
+	for _, nd := range s.theList {
+		nd.status = statusCG
+	}
+	config.dns = make(map[string][]dns.RR)
+	updateDNS(s)
+	srcs := make(map[string]int)
+	for _, rr := range config.dns["seed.sim.test.A"] {
+		nd := s.theList[newNodeAddr(rr.(*dns.A).A, 8333)]
+		srcs[nd.srcGroup]++
+	}
+	if srcs["7.7.0.0/16"] != dnsPerSource || srcs["6.6.0.0/16"] != maxDNSRecords-dnsPerSource {
+		t.Errorf("dns answer sources: %v", srcs)
+	}
+
+	// with few sources the answer is still filled
+	s.theList[newNodeAddr(net.ParseIP("30.1.0.1"), 8333)].status = statusWG
+	updateDNS(s)
+	if n := len(config.dns["seed.sim.test.A"]); n != maxDNSRecords {
+		t.Errorf("dns answer has %v records expected: %v", n, maxDNSRecords)
+	}
+}
+
+func TestMakeTried(t *testing.T) {
+
+	s := &dnsseeder{port: 8333, maxSize: 1250}
+	s.theList = make(map[nodeAddr]*node)
+
+	var nds []*node
+	for i := 1; i <= 10; i++ {
+		nd := s.addNode(wire.NewNetAddressIPPort(net.IPv4(byte(i+20), 1, 1, 1), 8333, 0), fmt.Sprintf("%d.0.0.0/16", i))
+		if nd == nil {
+			t.Fatalf("unable to add node %v", i)
+		}
+		nds = append(nds, nd)
+	}
+
+	for _, nd := range nds[:5] {
+		s.setStatus(nd, statusCG)
+		s.makeTried(nd)
+	}
+	if s.tables.nNew != 5 || s.tables.nTried != 5 {
+		t.Errorf("new: %v tried: %v expected: 5 and 5", s.tables.nNew, s.tables.nTried)
+	}
+	if s.tables.tried[nds[0].bucket][nds[0].slot] != nds[0] {
+		t.Errorf("tried node not in its tried slot")
+	}
+
+	// put a node in the tried slot that another node will need
+	occupy := func(old, nd *node) {
+		b, slot := s.tables.triedPos(nd.addr.String(), nd.group)
+		s.clearSlot(old)
+		s.tables.bucketFor(tableTried, b)[slot] = old
+		old.table, old.bucket, old.slot = tableTried, b, slot
+		s.tables.nTried++
+	}
+
+	// a node that has gone bad is moved back to the new table when its tried slot is needed
+	old, nd := nds[0], nds[5]
+	s.setStatus(old, statusWG)
+	occupy(old, nd)
+	s.setStatus(nd, statusCG)
+	s.makeTried(nd)
+	if old.table != tableNew || nd.table != tableTried {
+		t.Errorf("bad node table: %s new node table: %s", table2str(old.table), table2str(nd.table))
+	}
+
+	// a good node keeps its tried slot and the new node stays in the new table
+	good, nd := nds[2], nds[6]
+	occupy(good, nd)
+	s.setStatus(nd, statusCG)
+	s.makeTried(nd)
+	if good.table != tableTried || nd.table != tableNew {
+		t.Errorf("good node table: %s new node table: %s", table2str(good.table), table2str(nd.table))
+	}
+
+	// removing a node frees its slot
+	s.removeNode(nds[1].addr)
+	if s.tables.tried[nds[1].bucket][nds[1].slot] != nil || s.tables.nTried != 4 {
+		t.Errorf("removed node still in the tried table. tried: %v", s.tables.nTried)
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JBanRule is one rule in the ban list file. Action is ban or allow and CIDR can be a
+// single address or a range. An empty Network applies the rule to all networks and a
+// zero Expires means the rule never expires. An allow rule overrides any ban rule
+type JBanRule struct {
+	Action  string
+	CIDR    string
+	Network string    `json:",omitempty"`
+	Expires time.Time `json:",omitempty"`
+	Reason  string    `json:",omitempty"`
+}
+
+// banRule is the parsed version of a JBanRule
+type banRule struct {
+	allow   bool
+	ipnet   *net.IPNet
+	network string
+	expires time.Time
+	reason  string
+}
+
+// banList holds the operator ban and allow rules loaded from the ban list file
+type banList struct {
+	file   string       // file the rules are loaded from
+	rules  []banRule    // current rules
+	loaded time.Time    // when the rules were last loaded
+	mtx    sync.RWMutex // protect the rules
+}
+
+// newBanList creates a ban list and loads the rules from the file
+func newBanList(fName string) (*banList, error) {
+	bl := &banList{file: fName}
+	if err := bl.reload(); err != nil {
+		return nil, err
+	}
+	return bl, nil
+}
+
+// reload reads the ban list file and replaces the current rules. If the file has
+// any errors the current rules are kept
+func (bl *banList) reload() error {
+
+	j, err := ioutil.ReadFile(bl.file)
+	if err != nil {
+		return fmt.Errorf("Error reading ban list file: %v", err)
+	}
+
+	var jrules []JBanRule
+	if err = json.Unmarshal(j, &jrules); err != nil {
+		return fmt.Errorf("Error decoding ban list file %s: %v", bl.file, err)
+	}
+
+	rules := make([]banRule, 0, len(jrules))
+	for i, jr := range jrules {
+		r := banRule{network: jr.Network, expires: jr.Expires, reason: jr.Reason}
+
+		switch strings.ToLower(jr.Action) {
+		case "ban":
+		case "allow":
+			r.allow = true
+		default:
+			return fmt.Errorf("Error in ban list rule %v: unknown action %s", i+1, jr.Action)
+		}
+
+		// a single address is a range of one
+		cidr := jr.CIDR
+		if strings.Contains(cidr, "/") == false {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		if _, r.ipnet, err = net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("Error in ban list rule %v: %v", i+1, err)
+		}
+		rules = append(rules, r)
+	}
+
+	bl.mtx.Lock()
+	bl.rules = rules
+	bl.loaded = time.Now()
+	bl.mtx.Unlock()
+
+	log.Printf("status - loaded %v rules from ban list %s\n", len(rules), bl.file)
+	return nil
+}
+
+// check returns true and the reason if the address is banned for the network
+func (bl *banList) check(ip net.IP, network string) (bool, string) {
+
+	// no ban list file so nothing is banned
+	if bl == nil {
+		return false, ""
+	}
+
+	bl.mtx.RLock()
+	defer bl.mtx.RUnlock()
+
+	now := time.Now()
+	banned, reason := false, ""
+	for _, r := range bl.rules {
+		if r.network != "" && r.network != network {
+			continue
+		}
+		if r.expires.IsZero() == false && now.After(r.expires) {
+			continue
+		}
+		if r.ipnet.Contains(ip) == false {
+			continue
+		}
+		if r.allow {
+			return false, ""
+		}
+		if banned == false {
+			banned, reason = true, r.reason
+		}
+	}
+	return banned, reason
+}
+
+// forNetwork returns the rules that apply to the network including expired rules
+func (bl *banList) forNetwork(network string) []banRule {
+	if bl == nil {
+		return nil
+	}
+	bl.mtx.RLock()
+	defer bl.mtx.RUnlock()
+
+	var rules []banRule
+	for _, r := range bl.rules {
+		if r.network == "" || r.network == network {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// isBanned returns true and the reason if the operator has banned the address for this seeder
+func (s *dnsseeder) isBanned(ip net.IP) (bool, string) {
+	return config.bans.check(ip, s.name)
+}
+
+/*
+
+ */
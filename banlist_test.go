@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestBanList(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fName := filepath.Join(dir, "bans.json")
+	expired := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	rules := fmt.Sprintf(`[
+	{"Action": "ban", "CIDR": "1.2.0.0/16", "Reason": "bad hoster"},
+	{"Action": "allow", "CIDR": "1.2.3.4"},
+	{"Action": "ban", "CIDR": "5.6.7.8", "Network": "OtherNet", "Reason": "other network only"},
+	{"Action": "ban", "CIDR": "9.9.9.9", "Expires": "%s"},
+	{"Action": "ban", "CIDR": "2a01:4f8::/32", "Reason": "v6 range"}
+	]`, expired)
+	if err = ioutil.WriteFile(fName, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bl, err := newBanList(fName)
+	if err != nil {
+		t.Fatalf("unable to load ban list: %v", err)
+	}
+
+	var tests = []struct {
+		ip      string
+		network string
+		banned  bool
+		reason  string
+	}{
+		{"1.2.4.4", "SimNet", true, "bad hoster"},
+		{"1.2.3.4", "SimNet", false, ""},
+		{"5.6.7.8", "SimNet", false, ""},
+		{"5.6.7.8", "OtherNet", true, "other network only"},
+		{"9.9.9.9", "SimNet", false, ""},
+		{"2a01:4f8::1", "SimNet", true, "v6 range"},
+		{"8.8.8.8", "SimNet", false, ""},
+	}
+
+	for _, tt := range tests {
+		b, reason := bl.check(net.ParseIP(tt.ip), tt.network)
+		if b != tt.banned || reason != tt.reason {
+			t.Errorf("ip: %s network: %s banned: %v %q expected: %v %q", tt.ip, tt.network, b, reason, tt.banned, tt.reason)
+		}
+	}
+
+	// a bad file keeps the current rules
+	if err = ioutil.WriteFile(fName, []byte(`[{"Action": "block", "CIDR": "8.8.8.8"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = bl.reload(); err == nil {
+		t.Errorf("ban list with unknown action was loaded")
+	}
+	if b, _ := bl.check(net.ParseIP("1.2.4.4"), "SimNet"); b == false {
+		t.Errorf("rules lost after failed reload")
+	}
+
+	// a reload replaces the rules
+	if err = ioutil.WriteFile(fName, []byte(`[{"Action": "ban", "CIDR": "8.8.8.8"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = bl.reload(); err != nil {
+		t.Fatalf("unable to reload ban list: %v", err)
+	}
+	if b, _ := bl.check(net.ParseIP("1.2.4.4"), "SimNet"); b == true {
+		t.Errorf("old rule still applied after reload")
+	}
+
+	// banned addresses are not added to theList
+	config.bans = bl
+	defer func() { config.bans = nil }()
+
+	s := &dnsseeder{name: "SimNet", port: 8333, maxSize: 1250}
+	s.theList = make(map[nodeAddr]*node)
+	if s.addNode(wire.NewNetAddressIPPort(net.ParseIP("8.8.8.8"), 8333, 0), "") != nil {
+		t.Errorf("banned address added to theList")
+	}
+	if s.counts.Banned != 1 {
+		t.Errorf("banned count: %v expected: 1", s.counts.Banned)
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	defMinGood    = 10  // re-bootstrap when there are fewer statusCG nodes than this
+	bootMinDelay  = 5   // minutes to wait after a bootstrap before the first retry
+	bootMaxDelay  = 360 // max minutes between bootstrap attempts
+	maxBootEvents = 10  // number of bootstrap events kept for the web interface
+)
+
+// bootEvent records one bootstrap from the other seeders for the web interface
+type bootEvent struct {
+	When   time.Time // when the bootstrap completed
+	Reason string    // why the bootstrap was run
+	Found  int       // addresses returned by the seeders and initial ips
+	Added  int       // new addresses added to theList
+	Errors int       // seeder lookups that failed
+}
+
+// bootAddr is an address returned by a seeder and the source group it came from
+type bootAddr struct {
+	na  *wire.NetAddress
+	src string
+}
+
+// bootResult holds the addresses from one run of the seeder lookups
+type bootResult struct {
+	reason string
+	addrs  []bootAddr
+	errors int
+}
+
+// lookupSeeders queries the other seeders for the network for standard port nodes and for
+// non standard port nodes using the nonstd. host name, then asks the local node if there is
+// one. It does not touch theList so it can run without the seeder lock
+func (s *dnsseeder) lookupSeeders(reason string, seeders []string, rpc *rpcSource) *bootResult {
+
+	br := &bootResult{reason: reason}
+
+	if rpc != nil {
+		addrs, err := rpc.addresses()
+		if err != nil {
+			log.Printf("%s: unable to get addresses from the local node %v\n", s.name, err)
+			br.errors++
+		}
+		br.addrs = append(br.addrs, addrs...)
+	}
+
+	for _, aseeder := range seeders {
+
+		if aseeder == "" {
+			continue
+		}
+		newRRs, err := s.resolver.LookupHost(aseeder)
+		if err != nil {
+			log.Printf("%s: unable to do lookup to seeder %s %v\n", s.name, aseeder, err)
+			br.errors++
+			continue
+		}
+
+		for _, ip := range newRRs {
+			if newIP := net.ParseIP(ip); newIP != nil {
+				// 1 at the end is the services flag
+				br.addrs = append(br.addrs, bootAddr{na: wire.NewNetAddressIPPort(newIP, s.port, 1), src: srcSeeder + aseeder})
+			}
+		}
+
+		// not all seeders serve non standard port nodes so a failure here is not an error
+		nonRRs, err := s.resolver.LookupHost("nonstd." + aseeder)
+		if err != nil {
+			if config.verbose {
+				log.Printf("%s: no non standard port nodes from seeder %s %v\n", s.name, aseeder, err)
+			}
+			continue
+		}
+		var ips []net.IP
+		for _, ip := range nonRRs {
+			if newIP := net.ParseIP(ip); newIP != nil {
+				ips = append(ips, newIP)
+			}
+		}
+		for _, na := range decodeNonStd(ips) {
+			br.addrs = append(br.addrs, bootAddr{na: na, src: srcSeeder + aseeder})
+		}
+	}
+	return br
+}
+
+// decodeNonStd matches the real and encoded addresses returned for a nonstd. host name
+// and returns the real address with its port. See getNonStdIP for the encoding. Only ipv4
+// nodes can be matched as the encoding of an ipv6 address does not identify it
+func decodeNonStd(ips []net.IP) []*wire.NetAddress {
+
+	var nas []*wire.NetAddress
+	for _, rip := range ips {
+		r4 := rip.To4()
+		if r4 == nil {
+			continue
+		}
+		crc := crc16(r4)
+		for _, eip := range ips {
+			e4 := eip.To4()
+			if e4 == nil || e4.Equal(r4) || uint16(e4[0])<<8|uint16(e4[1]) != crc {
+				continue
+			}
+			if port := uint16(e4[2])<<8 | uint16(e4[3]); port > minPort {
+				nas = append(nas, wire.NewNetAddressIPPort(rip, port, 1))
+				break
+			}
+		}
+	}
+	return nas
+}
+
+// addBootstrap adds the addresses from a seeder lookup to theList. If theList is still
+// empty the initial ips for the network are added as well. The bootstrap is recorded
+// and the number of new addresses is returned
+func (s *dnsseeder) addBootstrap(br *bootResult) int {
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	ev := bootEvent{Reason: br.reason, Found: len(br.addrs), Errors: br.errors}
+	imported := make(map[string]int)
+
+	for _, ba := range br.addrs {
+		if r := s.routable(ba.na.IP); r != routeOK {
+			s.countReject(r)
+			if config.verbose {
+				log.Printf("%s: ignoring %s address %s from %s\n", s.name, route2str(r), ba.na.IP, ba.src)
+			}
+			continue
+		}
+		if s.addNa(ba.na, ba.src) == true {
+			imported[ba.src]++
+			ev.Added++
+		}
+	}
+	if config.verbose {
+		for src, c := range imported {
+			log.Printf("%s: completed import of %v addresses from %s\n", s.name, c, src)
+		}
+	}
+
+	// load ip addresses into system and start crawling from them
+	if len(s.theList) == 0 && len(s.initialIPs) > 0 {
+		for _, initialIP := range s.initialIPs {
+			if newIP := net.ParseIP(initialIP); newIP != nil {
+				ev.Found++
+				if r := s.routable(newIP); r != routeOK {
+					s.countReject(r)
+					log.Printf("%s: ignoring %s initial IP %s. Set AllowPrivate for a private network\n", s.name, route2str(r), initialIP)
+					continue
+				}
+				// 1 at the end is the services flag
+				if x := s.addNa(wire.NewNetAddressIPPort(newIP, s.port, 1), srcInitial); x == true {
+					log.Printf("%s: crawling with initial IP %s \n", s.name, initialIP)
+					ev.Added++
+				}
+			}
+		}
+	}
+
+	ev.When = time.Now()
+	s.boots = append(s.boots, ev)
+	if len(s.boots) > maxBootEvents {
+		s.boots = s.boots[len(s.boots)-maxBootEvents:]
+	}
+	s.counts.mtx.Lock()
+	s.counts.Bootstraps++
+	s.counts.mtx.Unlock()
+
+	log.Printf("%s: bootstrap (%s) found %v addresses and added %v new nodes\n", s.name, br.reason, ev.Found, ev.Added)
+	return ev.Added
+}
+
+// needBootstrap returns the reason to bootstrap from the other seeders again or an empty
+// string if we have enough good nodes or it is too soon since the last attempt. Each attempt
+// that does not bring back enough good nodes doubles the wait before the next one
+func (s *dnsseeder) needBootstrap(now time.Time) string {
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var reason string
+	switch cg := int(s.statusCount[statusCG]); {
+	case len(s.theList) == 0:
+		reason = "empty node list"
+	case cg < s.minGood:
+		reason = fmt.Sprintf("%v good nodes below %v", cg, s.minGood)
+	default:
+		// we have recovered so the next problem starts with a short wait
+		s.bootDelay = 0
+		return ""
+	}
+
+	if s.booting == true || now.Before(s.nextBoot) {
+		return ""
+	}
+
+	if s.bootDelay == 0 {
+		s.bootDelay = time.Minute * bootMinDelay
+	} else if s.bootDelay *= 2; s.bootDelay > time.Minute*bootMaxDelay {
+		s.bootDelay = time.Minute * bootMaxDelay
+	}
+	s.nextBoot = now.Add(s.bootDelay)
+	s.booting = true
+	return reason
+}
+
+// startBootstrap runs the seeder and local node lookups in a goroutine so slow servers
+// do not hold up runSeeder. The result is sent back on bootChan
+func (s *dnsseeder) startBootstrap(reason string, bootChan chan *bootResult) {
+
+	s.mtx.RLock()
+	seeders, rpc := s.seeders, s.rpc
+	s.mtx.RUnlock()
+
+	log.Printf("%s: bootstrapping from the seeders - %s\n", s.name, reason)
+	go func() {
+		bootChan <- s.lookupSeeders(reason, seeders, rpc)
+	}()
+}
+
+// bootEnd adds the result of a bootstrap to theList and allows the next one to start
+func (s *dnsseeder) bootEnd(br *bootResult) {
+	s.addBootstrap(br)
+	s.mtx.Lock()
+	s.booting = false
+	s.mtx.Unlock()
+}
+
+// boots2str returns the last bootstrap event for display
+func (s *dnsseeder) boots2str() string {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if len(s.boots) == 0 {
+		return "none"
+	}
+	ev := s.boots[len(s.boots)-1]
+	return fmt.Sprintf("%s ago (%s) found: %v added: %v failed lookups: %v",
+		time.Since(ev.When).Round(time.Second).String(), ev.Reason, ev.Found, ev.Added, ev.Errors)
+}
+
+/*
+
+ */
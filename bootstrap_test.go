@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gombadi/dnsseeder/simpeer"
+)
+
+func TestDecodeNonStd(t *testing.T) {
+
+	// a nonstd answer holds the real address and the encoded address for each node
+	var ips []net.IP
+	for _, tt := range []struct {
+		ip   string
+		port uint16
+	}{
+		{"1.2.3.4", 18333},
+		{"5.6.7.8", 8334},
+	} {
+		rip := net.ParseIP(tt.ip)
+		ips = append(ips, rip, getNonStdIP(rip, tt.port))
+	}
+	ips = append(ips, net.ParseIP("9.9.9.9"), net.ParseIP("2a01:4f8::1"))
+
+	nas := decodeNonStd(ips)
+	if len(nas) != 2 {
+		t.Fatalf("decoded %v addresses expected: 2", len(nas))
+	}
+	if nas[0].IP.String() != "1.2.3.4" || nas[0].Port != 18333 || nas[1].IP.String() != "5.6.7.8" || nas[1].Port != 8334 {
+		t.Errorf("decoded %s:%v and %s:%v", nas[0].IP, nas[0].Port, nas[1].IP, nas[1].Port)
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+
+	sn := simpeer.NewNetwork()
+	sn.AddHost("seed.example.com", "1.2.3.4", "5.6.7.8", "2a01:4f8::1")
+	sn.AddHost("nonstd.seed.example.com", "9.8.7.6", getNonStdIP(net.ParseIP("9.8.7.6"), 18333).String())
+
+	s := newSimSeeder(t, sn, simNetwork("seed.example.com", "missing.example.com"))
+
+	added := s.addBootstrap(s.lookupSeeders("test", s.seeders, nil))
+	if added != 4 {
+		t.Errorf("bootstrap added %v nodes expected: 4", added)
+	}
+	if _, ok := s.theList[mustAddr("9.8.7.6:18333")]; ok == false {
+		t.Errorf("non standard port node not added")
+	}
+	if len(s.boots) != 1 || s.boots[0].Errors != 1 || s.counts.Bootstraps != 1 {
+		t.Errorf("bootstrap events: %+v count: %v", s.boots, s.counts.Bootstraps)
+	}
+
+	// no good nodes so the watchdog bootstraps with a growing wait between attempts
+	now := time.Now()
+	if reason := s.needBootstrap(now); reason == "" {
+		t.Fatalf("no bootstrap with %v good nodes", s.statusCount[statusCG])
+	}
+	if s.needBootstrap(now.Add(time.Hour)) != "" {
+		t.Errorf("bootstrap started while one was running")
+	}
+	s.bootEnd(&bootResult{reason: "test"})
+	if s.needBootstrap(now.Add(time.Minute)) != "" {
+		t.Errorf("bootstrap started before the backoff expired")
+	}
+	if s.needBootstrap(now.Add(time.Minute*bootMinDelay)) == "" {
+		t.Errorf("no bootstrap after the backoff expired")
+	}
+	if s.bootDelay != time.Minute*bootMinDelay*2 {
+		t.Errorf("bootstrap delay: %v expected: %v", s.bootDelay, time.Minute*bootMinDelay*2)
+	}
+	s.bootEnd(&bootResult{reason: "test"})
+
+	// enough good nodes resets the backoff
+	s.minGood = 1
+	for _, nd := range s.theList {
+		s.setStatus(nd, statusCG)
+	}
+	if s.needBootstrap(now.Add(time.Hour*24)) != "" || s.bootDelay != 0 {
+		t.Errorf("bootstrap with enough good nodes. delay: %v", s.bootDelay)
+	}
+}
+
+/*
+
+ */
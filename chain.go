@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	chainCheckDelay = 24 // hours between chain checks for a node that passed
+	maxHeaderRounds = 10 // max number of getheaders requests when checking the chain work
+)
+
+const (
+	// chain status of a node
+	chainUnknown = iota // not checked yet or the network has no checkpoints
+	chainOK             // headers build on our checkpoint
+	chainFork           // headers do not build on our checkpoint
+	chainStale          // node has not reached our checkpoint
+	chainLowWork        // node chain does not have the minimum chain work
+)
+
+// JCheckpoint is a known block on the correct chain for a network. ChainWork is the
+// total work up to and including the block in hex and is only needed on the last
+// checkpoint if the network has a MinChainWork
+type JCheckpoint struct {
+	Height    int32
+	Hash      string
+	ChainWork string `json:",omitempty"`
+}
+
+// checkpoint is the parsed version of a JCheckpoint
+type checkpoint struct {
+	height int32
+	hash   chainhash.Hash
+	work   *big.Int // total chain work up to this block or nil if not known
+}
+
+// loadCheckpoints validates the network checkpoints and min chain work and
+// adds them to the seeder
+func (s *dnsseeder) loadCheckpoints(jcps []JCheckpoint, minWork string) error {
+
+	s.checkpoints = []checkpoint{}
+	for _, jcp := range jcps {
+		h, err := chainhash.NewHashFromStr(jcp.Hash)
+		if err != nil {
+			return fmt.Errorf("Invalid checkpoint hash at height %v: %v", jcp.Height, err)
+		}
+		cp := checkpoint{height: jcp.Height, hash: *h}
+		if jcp.ChainWork != "" {
+			if cp.work = parseWork(jcp.ChainWork); cp.work == nil {
+				return fmt.Errorf("Invalid checkpoint chain work at height %v: %s", jcp.Height, jcp.ChainWork)
+			}
+		}
+		s.checkpoints = append(s.checkpoints, cp)
+	}
+	sort.Slice(s.checkpoints, func(i, j int) bool { return s.checkpoints[i].height < s.checkpoints[j].height })
+
+	s.minChainWork = nil
+	if minWork != "" {
+		if s.minChainWork = parseWork(minWork); s.minChainWork == nil {
+			return fmt.Errorf("Invalid MinChainWork: %s", minWork)
+		}
+		if len(s.checkpoints) == 0 {
+			return fmt.Errorf("MinChainWork needs at least one checkpoint")
+		}
+		if cp := s.checkpoints[len(s.checkpoints)-1]; cp.work == nil {
+			return fmt.Errorf("MinChainWork needs ChainWork for the last checkpoint at height %v", cp.height)
+		}
+	}
+	return nil
+}
+
+// parseWork converts a hex chain work string to a big.Int or nil if it is not valid
+func parseWork(w string) *big.Int {
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(strings.ToLower(w), "0x"), 16)
+	if ok == false || n.Sign() < 0 {
+		return nil
+	}
+	return n
+}
+
+// needChainCheck returns true if the node is due to have its chain checked
+func (s *dnsseeder) needChainCheck(nd *node) bool {
+	if len(s.checkpoints) == 0 {
+		return false
+	}
+	return nd.chainStatus != chainOK || time.Since(nd.chainChecked) > time.Hour*chainCheckDelay
+}
+
+// checkChain is run after the handshake and asks the remote node for the headers that
+// follow our last checkpoint. A node on the same chain will return headers that build
+// on the checkpoint.
+func (s *dnsseeder) checkChain(conn net.Conn, r *result) (uint32, error) {
+
+	cp := &s.checkpoints[len(s.checkpoints)-1]
+	if r.lastBlock <= cp.height {
+		return chainStale, fmt.Errorf("remote height %v is not past our last checkpoint %v", r.lastBlock, cp.height)
+	}
+
+	headers, err := s.getHeaders(conn, r, &cp.hash)
+	if err != nil {
+		return chainUnknown, err
+	}
+	if len(headers) == 0 {
+		return chainStale, fmt.Errorf("no headers after checkpoint %v", cp.height)
+	}
+	if headers[0].PrevBlock != cp.hash {
+		return chainFork, fmt.Errorf("headers do not build on checkpoint %v", cp.height)
+	}
+	if err = checkLinked(headers); err != nil {
+		return chainFork, err
+	}
+
+	if s.minChainWork == nil {
+		return chainOK, nil
+	}
+
+	// add up the work from the checkpoint until we reach the minimum or run out of headers
+	work := new(big.Int).Set(cp.work)
+	for rounds := 1; ; rounds++ {
+		for _, h := range headers {
+			work.Add(work, calcWork(h.Bits))
+		}
+		if work.Cmp(s.minChainWork) >= 0 {
+			return chainOK, nil
+		}
+		if len(headers) < wire.MaxBlockHeadersPerMsg || rounds >= maxHeaderRounds {
+			return chainLowWork, fmt.Errorf("chain work %x is below the minimum", work)
+		}
+
+		last := headers[len(headers)-1].BlockHash()
+		next, err := s.getHeaders(conn, r, &last)
+		if err != nil {
+			return chainUnknown, err
+		}
+		if len(next) == 0 || next[0].PrevBlock != last {
+			return chainLowWork, fmt.Errorf("chain work %x is below the minimum", work)
+		}
+		if err = checkLinked(next); err != nil {
+			return chainFork, err
+		}
+		headers = next
+	}
+}
+
+// getHeaders sends a getheaders message with one locator hash and returns the
+// headers from the reply
+func (s *dnsseeder) getHeaders(conn net.Conn, r *result, locator *chainhash.Hash) ([]*wire.BlockHeader, error) {
+
+	msgGetHeaders := wire.NewMsgGetHeaders()
+	msgGetHeaders.ProtocolVersion = s.pver
+	msgGetHeaders.AddBlockLocatorHash(locator)
+
+	if err := s.codec.WriteMessage(conn, msgGetHeaders, s.pver, s.id); err != nil {
+		return nil, err
+	}
+
+	// ignore any other messages the node sends before the headers
+	for c := 0; c < 25; c++ {
+		msg, _, err := s.codec.ReadMessage(conn, s.pver, s.id)
+		if err != nil {
+			if _, ok := err.(*wire.MessageError); ok {
+				continue
+			}
+			return nil, err
+		}
+		if msg, ok := msg.(*wire.MsgHeaders); ok {
+			return msg.Headers, nil
+		}
+		if config.debug {
+			log.Printf("%s - debug - %s - ignoring message waiting for headers - %v\n", s.name, r.node, msg.Command())
+		}
+	}
+	return nil, errors.New("did not receive headers in first 25 messages")
+}
+
+// checkLinked makes sure each header builds on the one before it
+func checkLinked(headers []*wire.BlockHeader) error {
+	for i := 1; i < len(headers); i++ {
+		if headers[i].PrevBlock != headers[i-1].BlockHash() {
+			return fmt.Errorf("header %v does not build on the previous header", i)
+		}
+	}
+	return nil
+}
+
+// calcWork returns the work represented by the difficulty bits of a block
+// which is 2^256 / (target+1)
+func calcWork(bits uint32) *big.Int {
+
+	// convert the compact representation to the target
+	mantissa := int64(bits & 0x007fffff)
+	exponent := uint(bits >> 24)
+	var target *big.Int
+	if exponent <= 3 {
+		target = big.NewInt(mantissa >> (8 * (3 - exponent)))
+	} else {
+		target = new(big.Int).Lsh(big.NewInt(mantissa), 8*(exponent-3))
+	}
+	if bits&0x00800000 != 0 || target.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+
+	target.Add(target, big.NewInt(1))
+	return target.Div(new(big.Int).Lsh(big.NewInt(1), 256), target)
+}
+
+// chain2str will return the string description of the chain status
+func chain2str(status uint32) string {
+	switch status {
+	case chainUnknown:
+		return "not checked"
+	case chainOK:
+		return "ok"
+	case chainFork:
+		return "wrong chain"
+	case chainStale:
+		return "stale chain"
+	case chainLowWork:
+		return "low chain work"
+	default:
+		return "Unknown"
+	}
+}
+
+/*
+
+ */
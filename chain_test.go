@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/gombadi/dnsseeder/simpeer"
+)
+
+func TestCalcWork(t *testing.T) {
+
+	var tests = []struct {
+		bits uint32
+		work string
+	}{
+		{0x1d00ffff, "100010001"}, // bitcoin genesis block
+		{0x207fffff, "2"},         // regression test
+		{0x01003456, "0"},
+	}
+
+	for _, atest := range tests {
+		if w := fmt.Sprintf("%x", calcWork(atest.bits)); w != atest.work {
+			t.Errorf("bits: %08x work: %s expected: %s", atest.bits, w, atest.work)
+		}
+	}
+}
+
+func TestCheckChain(t *testing.T) {
+
+	chain := simpeer.MakeChain(nil, 3000, 1)
+	fork := simpeer.MakeChain(chain[:1000], 2000, 2)
+
+	var tests = []struct {
+		ip        string
+		headers   []*wire.BlockHeader
+		lastBlock int32
+		minWork   string
+		chain     uint32
+		status    uint32
+	}{
+		{"1.1.1.1", chain, 2999, "", chainOK, statusCG},
+		{"2.2.2.2", fork, 2999, "", chainFork, statusNG},
+		{"3.3.3.3", chain[:1400], 1399, "", chainStale, statusNG},
+		{"4.4.4.4", chain[:1501], 2999, "", chainStale, statusNG},
+		{"5.5.5.5", chain, 2999, "0x1770", chainOK, statusCG},
+		{"6.6.6.6", chain, 2999, "0x1772", chainLowWork, statusNG},
+	}
+
+	for _, atest := range tests {
+		sn := simpeer.NewNetwork()
+		p := simpeer.NewPeer(wire.TestNet3, 70001, simpeer.Good)
+		p.Headers = atest.headers
+		p.LastBlock = atest.lastBlock
+		p.Addrs = []*wire.NetAddress{simAddr("7.7.7.7", 8333), simAddr("8.8.8.8", 8333)}
+		sn.AddPeer(atest.ip, 8333, p)
+
+		// regression test blocks have a work of 2 each
+		jnw := simNetwork()
+		jnw.Checkpoints = []JCheckpoint{
+			{Height: 1500, Hash: chain[1500].BlockHash().String(), ChainWork: "0xbba"},
+			{Height: 500, Hash: chain[500].BlockHash().String(), ChainWork: "0x3ea"},
+		}
+		jnw.MinChainWork = atest.minWork
+
+		s := newSimSeeder(t, sn, jnw)
+		s.addNa(simAddr(atest.ip, 8333), "")
+		crawlAll(s)
+
+		nd := s.theList[mustAddr(atest.ip+":8333")]
+		if nd.chainStatus != atest.chain || nd.status != atest.status {
+			t.Errorf("node: %s chain:status %s:%s expected: %s:%s last status: %s", atest.ip,
+				chain2str(nd.chainStatus), status2str(nd.status), chain2str(atest.chain), status2str(atest.status), nd.statusStr)
+		}
+		// addresses must only be accepted from nodes on the correct chain
+		if atest.chain == chainOK && len(s.theList) != 3 || atest.chain != chainOK && len(s.theList) != 1 {
+			t.Errorf("node: %s chain: %s nodes: %v", atest.ip, chain2str(nd.chainStatus), len(s.theList))
+		}
+		if atest.chain == chainOK && s.needChainCheck(nd) {
+			t.Errorf("node: %s passed the chain check but is still due for one", atest.ip)
+		}
+	}
+}
+
+func TestLoadCheckpoints(t *testing.T) {
+
+	var tests = []struct {
+		cps     []JCheckpoint
+		minWork string
+		ok      bool
+	}{
+		{nil, "", true},
+		{[]JCheckpoint{{Height: 1, Hash: "00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048"}}, "", true},
+		{[]JCheckpoint{{Height: 1, Hash: "not a hash"}}, "", false},
+		{[]JCheckpoint{{Height: 1, Hash: "00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048"}}, "0x10", false},
+		{[]JCheckpoint{{Height: 1, Hash: "00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048", ChainWork: "0x200020002"}}, "0x10", true},
+		{nil, "0x10", false},
+		{[]JCheckpoint{{Height: 1, Hash: "00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048", ChainWork: "xyz"}}, "", false},
+	}
+
+	for i, atest := range tests {
+		s := &dnsseeder{}
+		if err := s.loadCheckpoints(atest.cps, atest.minWork); (err == nil) != atest.ok {
+			t.Errorf("test: %v error: %v expected ok: %v", i, err, atest.ok)
+		}
+	}
+}
+
+func TestProbeBlocks(t *testing.T) {
+
+	chain := simpeer.MakeChain(nil, 3000, 1)
+
+	var tests = []struct {
+		ip       string
+		services wire.ServiceFlag
+		noBlocks bool
+		probe    uint32
+		served   bool
+	}{
+		{"1.1.1.1", wire.SFNodeNetwork, false, probeOK, true},
+		{"2.2.2.2", wire.SFNodeNetwork, true, probeFail, false},
+		{"3.3.3.3", wire.SFNodeBloom, true, probeUnknown, true},
+	}
+
+	for _, atest := range tests {
+		sn := simpeer.NewNetwork()
+		p := simpeer.NewPeer(wire.TestNet3, 70001, simpeer.Good)
+		p.Headers = chain
+		p.LastBlock = 2999
+		p.Services = atest.services
+		p.NoBlocks = atest.noBlocks
+		p.Addrs = []*wire.NetAddress{simAddr("7.7.7.7", 8333), simAddr("8.8.8.8", 8333)}
+		sn.AddPeer(atest.ip, 8333, p)
+
+		jnw := simNetwork()
+		jnw.Checkpoints = []JCheckpoint{
+			{Height: 500, Hash: chain[500].BlockHash().String()},
+			{Height: 1500, Hash: chain[1500].BlockHash().String()},
+		}
+		jnw.ProbeInterval = 24
+
+		s := newSimSeeder(t, sn, jnw)
+		s.addNa(simAddr(atest.ip, 8333), "")
+		crawlAll(s)
+		updateDNS(s)
+
+		nd := s.theList[mustAddr(atest.ip+":8333")]
+		if nd.probeStatus != atest.probe || nd.status != statusCG {
+			t.Errorf("node: %s probe:status %s:%s expected: %s:statusCG last status: %s", atest.ip,
+				probe2str(nd.probeStatus), status2str(nd.status), probe2str(atest.probe), nd.statusStr)
+		}
+		config.dnsmtx.RLock()
+		served := len(config.dns["seed.sim.test.A"]) == 1
+		config.dnsmtx.RUnlock()
+		if served != atest.served {
+			t.Errorf("node: %s served: %v expected: %v", atest.ip, served, atest.served)
+		}
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+)
+
+const (
+	checkError   = "error"   // the network file will not load or the seeder will not work
+	checkWarning = "warning" // the network file loads but something looks wrong
+)
+
+// checkIssue is one problem found in a network file
+type checkIssue struct {
+	File     string
+	Network  string `json:",omitempty"`
+	Field    string `json:",omitempty"`
+	Severity string
+	Message  string
+}
+
+// checkNetwork is a summary of a network file that loaded
+type checkNetwork struct {
+	File     string
+	Name     string
+	ID       string
+	Port     uint16
+	DNSNames []string
+	Seeders  int
+	Resolved int
+}
+
+// checkReport is the result of checking all the network files
+type checkReport struct {
+	OK       bool
+	Errors   int
+	Warnings int
+	Networks []checkNetwork
+	Issues   []checkIssue
+}
+
+func (cr *checkReport) add(file, network, field, severity, msg string) {
+	cr.Issues = append(cr.Issues, checkIssue{File: file, Network: network, Field: field, Severity: severity, Message: msg})
+	if severity == checkError {
+		cr.Errors++
+	} else {
+		cr.Warnings++
+	}
+}
+
+// validHostname returns true if h is a dns name with at least two labels
+func validHostname(h string) bool {
+
+	h = strings.TrimSuffix(h, ".")
+	if len(h) == 0 || len(h) > 253 || net.ParseIP(h) != nil {
+		return false
+	}
+	labels := strings.Split(h, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, l := range labels {
+		if len(l) == 0 || len(l) > 63 || l[0] == '-' || l[len(l)-1] == '-' {
+			return false
+		}
+		for _, c := range l {
+			if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// checkFields checks the fields of a network file and adds every problem found to the
+// report. initNetwork stops at the first problem so it is only used as a final check
+func checkFields(cr *checkReport, file string, jnw JNetwork) {
+
+	add := func(field, severity, format string, a ...interface{}) {
+		cr.add(file, jnw.Name, field, severity, fmt.Sprintf(format, a...))
+	}
+
+	if jnw.Name == "" {
+		add("Name", checkError, "No network name supplied")
+	}
+	if _, err := loadDNSNames(jnw.DNSName); err != nil {
+		add("DNSName", checkError, "%v", err)
+	}
+	for _, jn := range jnw.DNSName {
+		if jn.Name != "" && validHostname(jn.Name) == false {
+			add("DNSName", checkError, "Invalid DNS Hostname %s", jn.Name)
+		}
+		if jn.TTL != 0 && jn.TTL < 60 {
+			add("DNSName", checkWarning, "TTL %v for %s will be raised to 60", jn.TTL, jn.Name)
+		}
+	}
+	if _, err := networkMagic(jnw); err != nil {
+		add("ID", checkError, "%v", err)
+	}
+	switch {
+	case jnw.Port == 0:
+		add("Port", checkError, "Invalid port supplied: 0")
+	case jnw.Port < 1024:
+		add("Port", checkWarning, "Port %v is a privileged port and unlikely to be used by nodes", jnw.Port)
+	}
+	if jnw.Pver == 0 {
+		add("Pver", checkWarning, "No protocol version supplied")
+	}
+	if jnw.TTL < 60 {
+		add("TTL", checkWarning, "TTL %v will be raised to 60", jnw.TTL)
+	}
+
+	for _, ip := range jnw.InitialIPs {
+		switch nip := net.ParseIP(ip); {
+		case nip == nil:
+			add("InitialIPs", checkError, "Invalid ip address %s", ip)
+		case nip.IsUnspecified():
+			add("InitialIPs", checkWarning, "%s is a placeholder and will be ignored", ip)
+		}
+	}
+	for _, sd := range jnw.Seeders {
+		if validHostname(sd) == false {
+			add("Seeders", checkError, "Invalid seeder hostname %s", sd)
+		}
+	}
+	if len(jnw.Seeders) == 0 && len(jnw.InitialIPs) == 0 && jnw.RPC == nil {
+		add("Seeders", checkWarning, "No Seeders, InitialIPs or RPC so nodes can only come from a snapshot or an import")
+	}
+
+	if jnw.BindIPv4 != "" {
+		if ip := net.ParseIP(jnw.BindIPv4); ip == nil || ip.To4() == nil {
+			add("BindIPv4", checkError, "Invalid BindIPv4 address: %s", jnw.BindIPv4)
+		}
+	}
+	if jnw.BindIPv6 != "" {
+		if ip := net.ParseIP(jnw.BindIPv6); ip == nil || ip.To4() != nil {
+			add("BindIPv6", checkError, "Invalid BindIPv6 address: %s", jnw.BindIPv6)
+		}
+	}
+	if jnw.MaxStart != nil {
+		if err := checkMaxStart(jnw.MaxStart); err != nil {
+			add("MaxStart", checkError, "%v", err)
+		}
+	}
+	if _, err := newCodec(jnw.Codec, jnw.Checksum); err != nil {
+		add("Codec", checkError, "%v", err)
+	}
+	if jnw.RPC != nil {
+		if _, err := newRPCSource(*jnw.RPC); err != nil {
+			add("RPC", checkError, "%v", err)
+		}
+	}
+}
+
+// checkNetworks loads every network file and reports all the problems found rather
+// than stopping at the first one. If r is not nil the seeder hostnames are looked up
+func checkNetworks(files []string, r resolver) *checkReport {
+
+	cr := &checkReport{Networks: []checkNetwork{}, Issues: []checkIssue{}}
+	seeders := make(map[string]*dnsseeder)
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			cr.add(file, "", "", checkError, fmt.Sprintf("Error reading network file: %v", err))
+			continue
+		}
+		jnw, notes, err := decodeNetwork(data)
+		if err != nil {
+			cr.add(file, "", "", checkError, fmt.Sprintf("Error decoding network file: %v", err))
+			continue
+		}
+		if len(notes) > 0 {
+			cr.add(file, jnw.Name, "", checkWarning, "Old field names converted: "+strings.Join(notes, ", ")+". Run migrate-config to update the file")
+		}
+
+		errs := cr.Errors
+		checkFields(cr, file, jnw)
+		s, err := initNetwork(jnw)
+		if err != nil {
+			if cr.Errors == errs {
+				cr.add(file, jnw.Name, "", checkError, err.Error())
+			}
+			continue
+		}
+
+		for _, dup := range duplicateSeeder(s, seeders) {
+			cr.add(file, s.name, "", checkError, dup.Error())
+		}
+		if _, ok := seeders[s.name]; ok == false {
+			seeders[s.name] = s
+		}
+
+		cn := checkNetwork{File: file, Name: s.name, ID: fmt.Sprintf("0x%08x", uint32(s.id)), Port: s.port, Seeders: len(s.seeders)}
+		for _, n := range s.dnsNames {
+			cn.DNSNames = append(cn.DNSNames, n.host)
+		}
+		if r != nil {
+			for _, sd := range s.seeders {
+				if ips, err := r.LookupHost(sd); err != nil || len(ips) == 0 {
+					cr.add(file, s.name, "Seeders", checkWarning, fmt.Sprintf("Seeder %s does not resolve: %v", sd, err))
+				} else {
+					cn.Resolved++
+				}
+			}
+			if len(s.seeders) > 0 && cn.Resolved == 0 && len(s.initialIPs) == 0 && s.rpc == nil {
+				cr.add(file, s.name, "Seeders", checkError, "None of the seeders resolve so no nodes can be found")
+			}
+		}
+		cr.Networks = append(cr.Networks, cn)
+	}
+
+	cr.OK = cr.Errors == 0
+	return cr
+}
+
+// runCheck is the check subcommand. It writes a json report about the network files
+// and returns an error if any of them has errors
+func runCheck(args []string) error {
+
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	nf := fs.String("netfile", "", "List of json config files to check")
+	resolve := fs.Bool("resolve", true, "Check that the seeder hostnames resolve")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s check -netfile <file[,file2]> [-resolve=false]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *nf == "" {
+		fs.Usage()
+		return fmt.Errorf("Error - check needs -netfile")
+	}
+
+	var r resolver
+	if *resolve {
+		r = netResolver{}
+	}
+	cr := checkNetworks(strings.Split(*nf, ","), r)
+
+	j, err := json.MarshalIndent(cr, "", " ")
+	if err != nil {
+		return fmt.Errorf("Error encoding report: %v", err)
+	}
+	fmt.Printf("%s\n", j)
+
+	if cr.OK == false {
+		return fmt.Errorf("Error - %v errors found in the network files", cr.Errors)
+	}
+	return nil
+}
+
+/*
+
+ */
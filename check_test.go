@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gombadi/dnsseeder/simpeer"
+)
+
+func TestValidHostname(t *testing.T) {
+
+	var tests = []struct {
+		host string
+		ok   bool
+	}{
+		{"seed.example.com", true},
+		{"seed.example.com.", true},
+		{"dnsseed_1.example-2.com", true},
+		{"localhost", false},
+		{"", false},
+		{"1.2.3.4", false},
+		{"seed..example.com", false},
+		{"-seed.example.com", false},
+		{"seed.example.com/x", false},
+		{"seed example.com", false},
+		{strings.Repeat("a", 64) + ".com", false},
+	}
+	for _, tt := range tests {
+		if got := validHostname(tt.host); got != tt.ok {
+			t.Errorf("host: %q valid: %v expected: %v", tt.host, got, tt.ok)
+		}
+	}
+}
+
+func TestCheckNetworks(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(fName, j string) string {
+		fName = filepath.Join(dir, fName)
+		if err := ioutil.WriteFile(fName, []byte(j), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return fName
+	}
+
+	good := write("good.json", `{"Name": "NetA", "ID": "0x0709110b", "Port": 8333, "Pver": 70001, "DNSName": "seed.a.test", "TTL": 600,
+		"Seeders": ["seed.one.test", "seed.two.test"]}`)
+	// every problem in this file is reported, not just the first one
+	bad := write("bad.json", `{"Name": "NetB", "ID": "0xzz", "Port": 80, "DNSName": "seed b", "TTL": 30,
+		"InitialIPs": ["1.2.3.400", "0.0.0.0"], "Seeders": ["seed.one.test", "bad_seed"], "Checksum": "md5", "MaxStart": [0, 0, 0, 0]}`)
+	dup := write("dup.json", `{"Name": "NetC", "ID": "0x0709110b", "Port": 8333, "Pver": 70001, "DNSName": "seed.a.test", "TTL": 600,
+		"Seeders": ["seed.gone.test"]}`)
+	typo := write("typo.json", `{"Name": "NetD", "Seeder": "seed.one.test"}`)
+	legacy := write("legacy.json", `{"Name": "NetE", "ID": "0x0709110d", "Port": 8333, "Pver": 70001, "DNSName": "seed.e.test", "TTL": 600,
+		"Seeder1": "seed.one.test"}`)
+	missing := filepath.Join(dir, "missing.json")
+
+	sn := simpeer.NewNetwork()
+	sn.AddHost("seed.one.test", "1.2.3.4")
+	sn.AddHost("seed.two.test", "1.2.3.5")
+
+	cr := checkNetworks([]string{good, bad, dup, typo, legacy, missing}, sn)
+	if cr.OK {
+		t.Errorf("report is OK with errors in the files")
+	}
+
+	count := func(file, field, severity string) int {
+		n := 0
+		for _, is := range cr.Issues {
+			if is.File == file && (field == "" || is.Field == field) && is.Severity == severity {
+				n++
+			}
+		}
+		return n
+	}
+
+	if n := count(good, "", checkError) + count(good, "", checkWarning); n != 0 {
+		t.Errorf("good file has %v issues", n)
+	}
+	var fields = []struct {
+		field    string
+		severity string
+		n        int
+	}{
+		{"ID", checkError, 1},
+		{"Port", checkWarning, 1},
+		{"Pver", checkWarning, 1},
+		{"DNSName", checkError, 1},
+		{"TTL", checkWarning, 1},
+		{"InitialIPs", checkError, 1},
+		{"InitialIPs", checkWarning, 1},
+		{"Seeders", checkError, 1},
+		{"Codec", checkError, 1},
+		{"MaxStart", checkError, 1},
+	}
+	for _, tt := range fields {
+		if n := count(bad, tt.field, tt.severity); n != tt.n {
+			t.Errorf("bad file %s %s issues: %v expected: %v", tt.field, tt.severity, n, tt.n)
+		}
+	}
+
+	// the same magic and dns name as the good file and a seeder that does not resolve
+	if n := count(dup, "", checkError); n != 3 {
+		t.Errorf("duplicate file has %v errors expected 3 - %+v", n, cr.Issues)
+	}
+	if n := count(dup, "Seeders", checkWarning); n != 1 {
+		t.Errorf("duplicate file has %v seeder warnings expected 1", n)
+	}
+	if count(typo, "", checkError) != 1 || count(missing, "", checkError) != 1 {
+		t.Errorf("typo or missing file not reported")
+	}
+	if count(legacy, "", checkWarning) != 1 || count(legacy, "", checkError) != 0 {
+		t.Errorf("legacy file issues: %+v", cr.Issues)
+	}
+
+	if len(cr.Networks) != 3 || cr.Networks[0].Name != "NetA" || cr.Networks[0].Resolved != 2 || cr.Networks[0].ID != "0x0709110b" {
+		t.Errorf("networks: %+v", cr.Networks)
+	}
+
+	// the report is json for scripts to read
+	j, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back checkReport
+	if err = json.Unmarshal(j, &back); err != nil || back.Errors != cr.Errors || len(back.Issues) != len(cr.Issues) {
+		t.Errorf("report does not round trip: %v", err)
+	}
+
+	if cr = checkNetworks([]string{good}, nil); cr.OK == false || cr.Networks[0].Resolved != 0 {
+		t.Errorf("good file without lookups: %+v", cr)
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	defCodec    = "bitcoin" // message framing used when the network file does not set one
+	defChecksum = "sha256d" // message checksum used when the network file does not set one
+)
+
+// codec reads and writes the p2p messages for a network. The methods match the
+// btcd wire functions so the bitcoin codec can use them directly
+type codec interface {
+	ReadMessage(r io.Reader, pver uint32, btcnet wire.BitcoinNet) (wire.Message, []byte, error)
+	WriteMessage(w io.Writer, msg wire.Message, pver uint32, btcnet wire.BitcoinNet) error
+	String() string
+}
+
+// checksums holds the hash functions that can be used for the message checksum.
+// The first 4 bytes of the hash of the payload are sent in the message header
+var checksums = map[string]func([]byte) []byte{
+	"sha256d": chainhash.DoubleHashB,
+	"sha256": func(b []byte) []byte {
+		h := sha256.Sum256(b)
+		return h[:]
+	},
+}
+
+// newCodec returns the codec for the Codec and Checksum names from the network file
+func newCodec(name, checksum string) (codec, error) {
+
+	if name == "" {
+		name = defCodec
+	}
+	if checksum == "" {
+		checksum = defChecksum
+	}
+	if name != defCodec {
+		return nil, fmt.Errorf("Unknown Codec %s. Must be %s", name, defCodec)
+	}
+
+	sum, ok := checksums[checksum]
+	if ok == false {
+		var names []string
+		for n := range checksums {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("Unknown Checksum %s. Must be one of %v", checksum, names)
+	}
+	if checksum == defChecksum {
+		return bitcoinCodec{}, nil
+	}
+	return checksumCodec{name: checksum, sum: sum}, nil
+}
+
+// bitcoinCodec uses the bitcoin message framing from btcd
+type bitcoinCodec struct{}
+
+func (bitcoinCodec) ReadMessage(r io.Reader, pver uint32, btcnet wire.BitcoinNet) (wire.Message, []byte, error) {
+	return wire.ReadMessage(r, pver, btcnet)
+}
+
+func (bitcoinCodec) WriteMessage(w io.Writer, msg wire.Message, pver uint32, btcnet wire.BitcoinNet) error {
+	return wire.WriteMessage(w, msg, pver, btcnet)
+}
+
+func (bitcoinCodec) String() string { return defCodec + " " + defChecksum }
+
+// checksumCodec uses the bitcoin message framing with a different checksum hash. The
+// checksum is checked and replaced with the bitcoin one so btcd can decode the message
+type checksumCodec struct {
+	name string
+	sum  func([]byte) []byte
+}
+
+func (c checksumCodec) ReadMessage(r io.Reader, pver uint32, btcnet wire.BitcoinNet) (wire.Message, []byte, error) {
+
+	hdr := make([]byte, wire.MessageHeaderSize)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, nil, err
+	}
+
+	// magic(4) command(12) length(4) checksum(4)
+	length := binary.LittleEndian.Uint32(hdr[16:20])
+	if length > wire.MaxMessagePayload {
+		return nil, nil, fmt.Errorf("message payload is too large - header indicates %v bytes, but max message payload is %v bytes",
+			length, wire.MaxMessagePayload)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(c.sum(payload)[:4], hdr[20:24]) == false {
+		return nil, nil, fmt.Errorf("payload checksum failed - header indicates %x, but actual checksum is %x", hdr[20:24], c.sum(payload)[:4])
+	}
+
+	copy(hdr[20:24], chainhash.DoubleHashB(payload)[:4])
+	return wire.ReadMessage(io.MultiReader(bytes.NewReader(hdr), bytes.NewReader(payload)), pver, btcnet)
+}
+
+func (c checksumCodec) WriteMessage(w io.Writer, msg wire.Message, pver uint32, btcnet wire.BitcoinNet) error {
+
+	var b bytes.Buffer
+	if err := wire.WriteMessage(&b, msg, pver, btcnet); err != nil {
+		return err
+	}
+	buf := b.Bytes()
+	copy(buf[20:24], c.sum(buf[wire.MessageHeaderSize:])[:4])
+	_, err := w.Write(buf)
+	return err
+}
+
+func (c checksumCodec) String() string { return defCodec + " " + c.name }
+
+/*
+
+ */
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/gombadi/dnsseeder/simpeer"
+)
+
+func TestNewCodec(t *testing.T) {
+
+	var tests = []struct {
+		name     string
+		checksum string
+		str      string
+		ok       bool
+	}{
+		{"", "", "bitcoin sha256d", true},
+		{"bitcoin", "sha256d", "bitcoin sha256d", true},
+		{"", "sha256", "bitcoin sha256", true},
+		{"groestl", "", "", false},
+		{"", "groestl", "", false},
+	}
+
+	for _, tt := range tests {
+		c, err := newCodec(tt.name, tt.checksum)
+		if (err == nil) != tt.ok {
+			t.Errorf("codec: %q checksum: %q error: %v", tt.name, tt.checksum, err)
+			continue
+		}
+		if err == nil && c.String() != tt.str {
+			t.Errorf("codec: %q checksum: %q is %s expected: %s", tt.name, tt.checksum, c, tt.str)
+		}
+	}
+}
+
+func TestChecksumCodec(t *testing.T) {
+
+	sha256Codec, _ := newCodec("", "sha256")
+	msg := wire.NewMsgPing(12345)
+
+	var b bytes.Buffer
+	if err := sha256Codec.WriteMessage(&b, msg, 70001, wire.TestNet3); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	raw := append([]byte(nil), b.Bytes()...)
+
+	got, _, err := sha256Codec.ReadMessage(&b, 70001, wire.TestNet3)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if ping, ok := got.(*wire.MsgPing); ok == false || ping.Nonce != 12345 {
+		t.Errorf("read %v expected ping 12345", got)
+	}
+
+	// each codec rejects the checksum of the other
+	if _, _, err = (bitcoinCodec{}).ReadMessage(bytes.NewReader(raw), 70001, wire.TestNet3); err == nil {
+		t.Errorf("bitcoin codec read a sha256 checksum")
+	}
+	b.Reset()
+	wire.WriteMessage(&b, msg, 70001, wire.TestNet3)
+	if _, _, err = sha256Codec.ReadMessage(&b, 70001, wire.TestNet3); err == nil {
+		t.Errorf("sha256 codec read a sha256d checksum")
+	}
+}
+
+func TestCrawlWithCodec(t *testing.T) {
+
+	jnw := simNetwork()
+	jnw.Checksum = "sha256"
+	c, _ := newCodec("", "sha256")
+
+	sn := simpeer.NewNetwork()
+	p := simpeer.NewPeer(wire.TestNet3, 70001, simpeer.Good)
+	p.Addrs = []*wire.NetAddress{simAddr("5.6.7.8", 8333), simAddr("5.6.7.9", 8333)}
+	p.Codec = c
+	sn.AddPeer("1.2.3.4", 8333, p)
+
+	// a seeder using the bitcoin checksum can not talk to the peer
+	s := newSimSeeder(t, sn, simNetwork())
+	s.addNa(simAddr("1.2.3.4", 8333), "")
+	crawlAll(s)
+	if nd := s.theList[mustAddr("1.2.3.4:8333")]; nd.status == statusCG {
+		t.Errorf("node crawled with the wrong checksum")
+	}
+
+	s = newSimSeeder(t, sn, jnw)
+	s.addNa(simAddr("1.2.3.4", 8333), "")
+	crawlAll(s)
+	if nd := s.theList[mustAddr("1.2.3.4:8333")]; nd.status != statusCG {
+		t.Errorf("node status: %s expected: statusCG %s", status2str(nd.status), nd.statusStr)
+	}
+	if len(s.theList) != 3 {
+		t.Errorf("theList has %v nodes expected: 3", len(s.theList))
+	}
+}
+
+/*
+
+ */
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"log"
 	"net"
-	"strconv"
 	"time"
 
 	"github.com/btcsuite/btcd/wire"
@@ -22,11 +21,7 @@ func (e *crawlError) Error() string {
 
 // crawlNode runs in a goroutine, crawls the remote ip and updates the master
 // list of currently active addresses
-func crawlNode(rc chan *result, s *dnsseeder, nd *node) {
-
-	res := &result{
-		node: net.JoinHostPort(nd.na.IP.String(), strconv.Itoa(int(nd.na.Port))),
-	}
+func crawlNode(rc chan *result, s *dnsseeder, res *result) {
 
 	// connect to the remote ip and ask them for their addr list
 	res.nas, res.msg = crawlIP(s, res)
@@ -41,7 +36,7 @@ func crawlNode(rc chan *result, s *dnsseeder, nd *node) {
 // crawlIP retrievs a slice of ip addresses from a client
 func crawlIP(s *dnsseeder, r *result) ([]*wire.NetAddress, *crawlError) {
 
-	conn, err := net.DialTimeout("tcp", r.node, time.Second*10)
+	conn, err := s.dialer.Dial("tcp", r.node.String())
 	if err != nil {
 		if config.debug {
 			log.Printf("%s - debug - Could not connect to %s - %v\n", s.name, r.node, err)
@@ -55,21 +50,22 @@ func crawlIP(s *dnsseeder, r *result) ([]*wire.NetAddress, *crawlError) {
 	}
 
 	// set a deadline for all comms to be done by. After this all i/o will error
-	conn.SetDeadline(time.Now().Add(time.Second * maxTo))
+	deadline := time.Now().Add(time.Second * time.Duration(s.maxTo))
+	conn.SetDeadline(deadline)
 
 	meAddr, youAddr := conn.LocalAddr(), conn.RemoteAddr()
 	me := wire.NewNetAddress(meAddr.(*net.TCPAddr), wire.SFNodeNetwork)
 	you := wire.NewNetAddress(youAddr.(*net.TCPAddr), wire.SFNodeNetwork)
 	msgver := wire.NewMsgVersion(me, you, nounce, 0)
 
-	err = wire.WriteMessage(conn, msgver, s.pver, s.id)
+	err = s.codec.WriteMessage(conn, msgver, s.pver, s.id)
 	if err != nil {
 		// Log and handle the error
 		return nil, &crawlError{"Write Version Message", err}
 	}
 
 	// first message received should be version
-	msg, _, err := wire.ReadMessage(conn, s.pver, s.id)
+	msg, _, err := s.codec.ReadMessage(conn, s.pver, s.id)
 	if err != nil {
 		// Log and handle the error
 		return nil, &crawlError{"Read message after sending Version", err}
@@ -93,13 +89,13 @@ func crawlIP(s *dnsseeder, r *result) ([]*wire.NetAddress, *crawlError) {
 	// send verack command
 	msgverack := wire.NewMsgVerAck()
 
-	err = wire.WriteMessage(conn, msgverack, s.pver, s.id)
+	err = s.codec.WriteMessage(conn, msgverack, s.pver, s.id)
 	if err != nil {
 		return nil, &crawlError{"writing message VerAck", err}
 	}
 
 	// second message received should be verack
-	msg, _, err = wire.ReadMessage(conn, s.pver, s.id)
+	msg, _, err = s.codec.ReadMessage(conn, s.pver, s.id)
 	if err != nil {
 		return nil, &crawlError{"reading expected Ver Ack from remote client", err}
 	}
@@ -113,15 +109,40 @@ func crawlIP(s *dnsseeder, r *result) ([]*wire.NetAddress, *crawlError) {
 		return nil, &crawlError{"Did not receive expected Ver Ack message from remote client", errors.New("")}
 	}
 
-	// if we get this far and if the seeder is full then don't ask for addresses. This will reduce bandwith usage while still
-	// confirming that we can connect to the remote node
-	if len(s.theList) > s.maxSize {
+	// make sure the node is on the same chain as our checkpoints before we trust anything else it says
+	if r.checkChain {
+		r.chain, err = s.checkChain(conn, r)
+		if r.chain != chainOK {
+			return nil, &crawlError{"checking remote chain", err}
+		}
+		if config.debug {
+			log.Printf("%s - debug - %s - chain check ok\n", s.name, r.node)
+		}
+	}
+
+	// make sure a node that claims to be a full node can serve historical blocks
+	if r.probe && r.services&wire.SFNodeNetwork != 0 {
+		r.probeStatus, err = s.probeBlocks(conn, r)
+		if r.probeStatus != probeOK {
+			if config.verbose {
+				log.Printf("%s: block probe failed for node: %s %v\n", s.name, r.node, err)
+			}
+			// the connection may not be usable after a failed probe so we stop here
+			// without asking for addresses
+			return nil, nil
+		}
+		conn.SetDeadline(deadline)
+	}
+
+	// if we get this far and the seeder is full and refusing new addresses then don't ask for them. This will reduce
+	// bandwith usage while still confirming that we can connect to the remote node
+	if r.getAddr == false {
 		return nil, nil
 	}
 	// send getaddr command
 	msgGetAddr := wire.NewMsgGetAddr()
 
-	err = wire.WriteMessage(conn, msgGetAddr, s.pver, s.id)
+	err = s.codec.WriteMessage(conn, msgGetAddr, s.pver, s.id)
 	if err != nil {
 		return nil, &crawlError{"writing Addr message to remote client", err}
 	}
@@ -134,7 +155,7 @@ func crawlIP(s *dnsseeder, r *result) ([]*wire.NetAddress, *crawlError) {
 		// Using the Bitcoin lib for the some networks means it does not understand some
 		// of the commands and will error. We can ignore these as we are only
 		// interested in the addr message and its content.
-		msgaddr, _, _ := wire.ReadMessage(conn, s.pver, s.id)
+		msgaddr, _, _ := s.codec.ReadMessage(conn, s.pver, s.id)
 		if msgaddr != nil {
 			switch msg := msgaddr.(type) {
 			case *wire.MsgAddr:
@@ -153,7 +174,7 @@ func crawlIP(s *dnsseeder, r *result) ([]*wire.NetAddress, *crawlError) {
 				// this means we need to wait for the second Addr message.
 				if len(peers) > 1 {
 					dowhile = false
-					return msg.AddrList, nil
+					return peers, nil
 				}
 			default:
 				if config.debug {
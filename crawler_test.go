@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/gombadi/dnsseeder/simpeer"
+	"github.com/miekg/dns"
+)
+
+// simNetwork returns the network config used by the tests
+func simNetwork(seeders ...string) JNetwork {
+	return JNetwork{
+		Name:    "SimNet",
+		ID:      "0x0709110b",
+		Port:    8333,
+		Pver:    70001,
+		DNSName: newDNSNames("seed.sim.test"),
+		TTL:     60,
+		Seeders: seeders,
+	}
+}
+
+// newSimSeeder returns a seeder that uses the simulated network for all
+// connections and dns lookups
+func newSimSeeder(t *testing.T, sn *simpeer.Network, jnw JNetwork) *dnsseeder {
+
+	if config.dns == nil {
+		config.dns = make(map[string][]dns.RR)
+	}
+
+	s, err := initNetwork(jnw)
+	if err != nil {
+		t.Fatalf("unable to create seeder: %v", err)
+	}
+	s.dialer = sn
+	s.resolver = sn
+
+	// a fixed bucket secret so the table positions are the same on every run
+	s.tables.key = [32]byte{}
+	return s
+}
+
+// crawlAll runs one startCrawlers tick and processes the results of every crawl it started
+func crawlAll(s *dnsseeder) {
+	rc := make(chan *result)
+	s.startCrawlers(rc)
+
+	s.mtx.RLock()
+	n := s.activeCrawls
+	s.mtx.RUnlock()
+
+	for i := 0; i < n; i++ {
+		s.processResult(<-rc)
+	}
+}
+
+func simAddr(ip string, port uint16) *wire.NetAddress {
+	return wire.NewNetAddressIPPort(net.ParseIP(ip), port, wire.SFNodeNetwork)
+}
+
+func TestCrawlEndToEnd(t *testing.T) {
+
+	sn := simpeer.NewNetwork()
+	sn.AddHost("seed.example.com", "1.2.3.4")
+
+	seed := simpeer.NewPeer(wire.TestNet3, 70001, simpeer.Good)
+	seed.Addrs = []*wire.NetAddress{
+		simAddr("5.6.7.8", 8333),
+		simAddr("5.6.7.9", 8333),
+		simAddr("5.6.7.10", 8333),
+		simAddr("5.6.7.11", 8333),
+		simAddr("5.6.7.12", 18444),
+	}
+	sn.AddPeer("1.2.3.4", 8333, seed)
+
+	// the other peers only know about nodes we already have
+	for _, p := range []struct {
+		ip   string
+		port uint16
+		b    simpeer.Behaviour
+	}{
+		{"5.6.7.8", 8333, simpeer.Good},
+		{"5.6.7.9", 8333, simpeer.Slow},
+		{"5.6.7.10", 8333, simpeer.Misbehaving},
+		{"5.6.7.12", 18444, simpeer.Good},
+	} {
+		np := simpeer.NewPeer(wire.TestNet3, 70001, p.b)
+		np.Addrs = seed.Addrs[:2]
+		sn.AddPeer(p.ip, p.port, np)
+	}
+
+	s := newSimSeeder(t, sn, simNetwork("seed.example.com", "missing.example.com"))
+
+	s.initSeeder()
+	if len(s.theList) != 1 {
+		t.Fatalf("initSeeder - nodes: %v expected: 1", len(s.theList))
+	}
+
+	// first crawl gets the address list from the seed node
+	crawlAll(s)
+	if len(s.theList) != 6 {
+		t.Fatalf("first crawl - nodes: %v expected: 6", len(s.theList))
+	}
+
+	// second crawl visits all the reported nodes
+	crawlAll(s)
+
+	var tests = []struct {
+		key    string
+		status uint32
+		rating uint32
+	}{
+		{"1.2.3.4:8333", statusCG, 0},
+		{"5.6.7.8:8333", statusCG, 0},
+		{"5.6.7.9:8333", statusCG, 0},
+		{"5.6.7.10:8333", statusRG, 25},
+		{"5.6.7.11:8333", statusRG, 25},
+		{"5.6.7.12:18444", statusCG, 0},
+	}
+	for _, atest := range tests {
+		nd, ok := s.theList[mustAddr(atest.key)]
+		if ok == false {
+			t.Errorf("node: %s missing from theList", atest.key)
+			continue
+		}
+		if nd.status != atest.status || nd.rating != atest.rating {
+			t.Errorf("node: %s status:rating %s:%v expected: %s:%v last status: %s",
+				atest.key, status2str(nd.status), nd.rating, status2str(atest.status), atest.rating, nd.statusStr)
+		}
+		if nd.crawlActive == true || nd.qIndex < 0 {
+			t.Errorf("node: %s not rescheduled after crawl", atest.key)
+		}
+	}
+	if sn.Dials("1.2.3.4:8333") != 1 {
+		t.Errorf("seed node crawled %v times expected: 1", sn.Dials("1.2.3.4:8333"))
+	}
+
+	s.auditNodes()
+	updateDNS(s)
+
+	config.dnsmtx.RLock()
+	v4std := config.dns["seed.sim.test.A"]
+	v4non := config.dns["nonstd.seed.sim.test.A"]
+	config.dnsmtx.RUnlock()
+
+	if len(v4std) != 3 {
+		t.Errorf("v4 standard dns records: %v expected: 3", len(v4std))
+	}
+	for _, rr := range v4std {
+		if ip := rr.(*dns.A).A.String(); ip == "5.6.7.10" || ip == "5.6.7.11" {
+			t.Errorf("unconfirmed node %s served in dns", ip)
+		}
+	}
+	// non standard port nodes produce the real ip and the encoded port ip
+	if len(v4non) != 2 {
+		t.Errorf("v4 non standard dns records: %v expected: 2", len(v4non))
+	}
+}
+
+func TestCrawlAddrFlood(t *testing.T) {
+
+	sn := simpeer.NewNetwork()
+	sn.AddHost("seed.example.com", "1.2.3.4")
+	sn.AddPeer("1.2.3.4", 8333, simpeer.NewPeer(wire.TestNet3, 70001, simpeer.AddrFlood))
+
+	s := newSimSeeder(t, sn, simNetwork("seed.example.com"))
+	s.initSeeder()
+	crawlAll(s)
+
+	// do not accept more than one third of maxSize addresses from one node
+	if added := len(s.theList) - 1; added > s.maxSize/3+1 {
+		t.Errorf("flooding node added %v addresses. max: %v", added, s.maxSize/3+1)
+	}
+	if s.theList[mustAddr("1.2.3.4:8333")].status != statusCG {
+		t.Errorf("flooding node status: %s expected: statusCG", status2str(s.theList[mustAddr("1.2.3.4:8333")].status))
+	}
+}
+
+func TestProcessResult(t *testing.T) {
+
+	var tests = []struct {
+		status    uint32
+		rating    uint32
+		fail      bool
+		expStatus uint32
+		expRating uint32
+	}{
+		{statusRG, 0, true, statusRG, 25},
+		{statusRG, 25, true, statusWG, 50},
+		{statusRG, 25, false, statusCG, 0},
+		{statusCG, 0, true, statusCG, 25},
+		{statusCG, 25, true, statusWG, 50},
+		{statusWG, 50, true, statusWG, 65},
+		{statusWG, 95, true, statusNG, 110},
+		{statusWG, 95, false, statusCG, 0},
+		{statusNG, 110, false, statusCG, 0},
+	}
+
+	s := newSimSeeder(t, simpeer.NewNetwork(), simNetwork())
+	na := simAddr("1.2.3.4", 8333)
+	s.addNa(na, "")
+	k := mustAddr("1.2.3.4:8333")
+	nd := s.theList[k]
+
+	for _, atest := range tests {
+		s.setStatus(nd, atest.status)
+		nd.rating = atest.rating
+
+		r := &result{node: k}
+		if atest.fail {
+			r.msg = &crawlError{"test", errors.New("connection refused")}
+		}
+
+		// processResult releases the crawl slots taken when the crawl started
+		acquireCrawl()
+		s.activeCrawls++
+		nd.crawlActive = true
+		s.processResult(r)
+
+		if nd.status != atest.expStatus || nd.rating != atest.expRating {
+			t.Errorf("from %s:%v fail: %v got %s:%v expected: %s:%v", status2str(atest.status), atest.rating, atest.fail,
+				status2str(nd.status), nd.rating, status2str(atest.expStatus), atest.expRating)
+		}
+		if s.statusCount[atest.expStatus] != 1 {
+			t.Errorf("status count for %s: %v expected: 1", status2str(atest.expStatus), s.statusCount[atest.expStatus])
+		}
+	}
+}
+
+func TestNoIPv6Route(t *testing.T) {
+
+	s := newSimSeeder(t, simpeer.NewNetwork(), simNetwork())
+	s.addNa(simAddr("2a01:4f8::1", 8333), "")
+	k := mustAddr("[2a01:4f8::1]:8333")
+	nd := s.theList[k]
+
+	// a failure due to our own lack of an ipv6 route must not count against the node
+	acquireCrawl()
+	s.activeCrawls++
+	nd.crawlActive = true
+	err := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ENETUNREACH)}
+	s.processResult(&result{node: k, msg: &crawlError{"", err}})
+
+	if nd.status != statusRG || nd.rating != 0 || nd.connectFails != 0 {
+		t.Errorf("node status:rating:fails %s:%v:%v expected: statusRG:0:0", status2str(nd.status), nd.rating, nd.connectFails)
+	}
+	if s.ipv6Down == false {
+		t.Errorf("ipv6 not marked down after no route error")
+	}
+
+	// while ipv6 is down the node is put back on the queue without being crawled
+	nd.nextCrawl = time.Now()
+	s.queueNode(nd)
+	s.startCrawlers(make(chan *result))
+	if s.activeCrawls != 0 || nd.nextCrawl.Before(time.Now()) {
+		t.Errorf("ipv6 node crawled while ipv6 is down")
+	}
+
+	// disabled address families are not accepted
+	s.disableIPv6 = true
+	if s.addNa(simAddr("2a01:4f8::2", 8333), "") {
+		t.Errorf("ipv6 node added with ipv6 disabled")
+	}
+}
+
+/*
+
+ */
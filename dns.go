@@ -2,83 +2,142 @@ package main
 
 import (
 	"log"
+	"net"
+	"strings"
+	"time"
 	//	"sync"
 
 	"github.com/miekg/dns"
 )
 
+const (
+	maxDNSRecords = 25 // max records in one dns answer
+	dnsPerSource  = 4  // nodes reported by one source group in an answer before other sources are preferred
+)
+
 // updateDNS updates the current slices of dns.RR so incoming requests get a
 // fast answer
 func updateDNS(s *dnsseeder) {
 
-	var rr4std, rr4non, rr6std, rr6non []dns.RR
+	// stop a network reload changing or removing the seeder while we update its records
+	config.smtx.RLock()
+	defer config.smtx.RUnlock()
+
+	// a seeder removed by a network reload must not add its records back
+	if s.isStopped() {
+		return
+	}
 
 	s.mtx.RLock()
 
-	// loop over each dns recprd type we need
-	for t := range []int{dnsV4Std, dnsV4Non, dnsV6Std, dnsV6Non} {
-		// FIXME above needs to be convertwd into one scan of theList if possible
+	// one scan of theList to find the nodes we can serve for each dns type
+	cands := make([][]*node, maxDNSTypes)
+	for _, nd := range s.theList {
+		if nd.status != statusCG {
+			continue
+		}
 
-		numRR := 0
+		// never serve a node that has failed a chain check
+		if nd.chainStatus > chainOK {
+			continue
+		}
+
+		// do not serve a node that claims to be a full node but can not return blocks
+		if nd.probeStatus == probeFail {
+			continue
+		}
+
+		// never serve an address that is not publicly routable
+		if s.routable(nd.na.IP) != routeOK {
+			continue
+		}
 
-		for _, nd := range s.theList {
+		// or one the operator has banned
+		if b, _ := s.isBanned(nd.na.IP); b {
+			continue
+		}
+
+		cands[nd.dnsType] = append(cands[nd.dnsType], nd)
+	}
+
+	// the same nodes are served for every dns name
+	chosen := make([][]*node, maxDNSTypes)
+	for _, t := range []uint32{dnsV4Std, dnsV4Non, dnsV6Std, dnsV6Non} {
+
+		// the most reliable nodes are the most likely to be served
+		rankNodes(cands[t])
+
+		numRR := 0
+		groups := make(map[string]int)
+		srcs := make(map[string]int)
+		var extra []*node
+		add := func(nd *node) {
+			chosen[t] = append(chosen[t], nd)
+			groups[nd.group]++
+			if t == dnsV4Non || t == dnsV6Non {
+				// the node is using a non standard port so the encoded port info is also in DNS
+				numRR += 2
+			} else {
+				numRR++
+			}
+		}
+		for _, nd := range cands[t] {
 			// when we reach max exit
-			if numRR >= 25 {
+			if numRR >= maxDNSRecords {
 				break
 			}
 
-			if nd.status != statusCG {
+			// limit how many nodes from one netgroup are in the answer
+			if s.maxDNSPerGrp > 0 && groups[nd.group] >= s.maxDNSPerGrp {
 				continue
 			}
 
-			if t == dnsV4Std || t == dnsV4Non {
-				if t == dnsV4Std && nd.dnsType == dnsV4Std {
-					r := new(dns.A)
-					r.Hdr = dns.RR_Header{Name: s.dnsHost + ".", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: s.ttl}
-					r.A = nd.na.IP
-					rr4std = append(rr4std, r)
-					numRR++
-				}
-
-				// if the node is using a non standard port then add the encoded port info to DNS
-				if t == dnsV4Non && nd.dnsType == dnsV4Non {
-					r := new(dns.A)
-					r.Hdr = dns.RR_Header{Name: "nonstd." + s.dnsHost + ".", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: s.ttl}
-					r.A = nd.na.IP
-					rr4non = append(rr4non, r)
-					numRR++
-					r = new(dns.A)
-					r.Hdr = dns.RR_Header{Name: "nonstd." + s.dnsHost + ".", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: s.ttl}
-					r.A = nd.nonstdIP
-					rr4non = append(rr4non, r)
-					numRR++
-				}
+			// nodes reported by a source that already has its share are only used
+			// if there are not enough nodes from other sources
+			if srcs[nd.srcGroup] >= dnsPerSource {
+				extra = append(extra, nd)
+				continue
 			}
-			if t == dnsV6Std || t == dnsV6Non {
-				if t == dnsV6Std && nd.dnsType == dnsV6Std {
-					r := new(dns.AAAA)
-					r.Hdr = dns.RR_Header{Name: s.dnsHost + ".", Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: s.ttl}
-					r.AAAA = nd.na.IP
-					rr6std = append(rr6std, r)
-					numRR++
-				}
-				// if the node is using a non standard port then add the encoded port info to DNS
-				if t == dnsV6Non && nd.dnsType == dnsV6Non {
-					r := new(dns.AAAA)
-					r.Hdr = dns.RR_Header{Name: "nonstd." + s.dnsHost + ".", Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: s.ttl}
-					r.AAAA = nd.na.IP
-					rr6non = append(rr6non, r)
-					numRR++
-					r = new(dns.AAAA)
-					r.Hdr = dns.RR_Header{Name: "nonstd." + s.dnsHost + ".", Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: s.ttl}
-					r.AAAA = nd.nonstdIP
-					rr6non = append(rr6non, r)
-					numRR++
-				}
+			srcs[nd.srcGroup]++
+			add(nd)
+		}
+		for _, nd := range extra {
+			if numRR >= maxDNSRecords {
+				break
 			}
-
+			if s.maxDNSPerGrp > 0 && groups[nd.group] >= s.maxDNSPerGrp {
+				continue
+			}
+			add(nd)
 		}
+	}
 
+	updated := time.Now()
+	records := make(map[string][]dns.RR)
+	for _, n := range s.dnsNames {
+		var rr4std, rr4non, rr6std, rr6non []dns.RR
+		ttl := s.ttlFor(n)
+		for _, t := range []uint32{dnsV4Std, dnsV4Non, dnsV6Std, dnsV6Non} {
+			for _, nd := range chosen[t] {
+				switch t {
+				case dnsV4Std:
+					rr4std = append(rr4std, newRR(n.host, nd.na.IP, ttl, false))
+				case dnsV4Non:
+					rr4non = append(rr4non, newRR("nonstd."+n.host, nd.na.IP, ttl, false), newRR("nonstd."+n.host, nd.nonstdIP, ttl, false))
+				case dnsV6Std:
+					rr6std = append(rr6std, newRR(n.host, nd.na.IP, ttl, true))
+				case dnsV6Non:
+					rr6non = append(rr6non, newRR("nonstd."+n.host, nd.na.IP, ttl, true), newRR("nonstd."+n.host, nd.nonstdIP, ttl, true))
+				}
+			}
+		}
+		records[n.host+".A"] = rr4std
+		records["nonstd."+n.host+".A"] = rr4non
+		records[n.host+".AAAA"] = rr6std
+		records["nonstd."+n.host+".AAAA"] = rr6non
+		if soa := s.soaRR(n, updated); soa != nil {
+			records[n.host+".SOA"] = soa
+		}
 	}
 
 	s.mtx.RUnlock()
@@ -86,24 +145,16 @@ func updateDNS(s *dnsseeder) {
 	config.dnsmtx.Lock()
 
 	// update the map holding the details for this seeder
-	for t := range []int{dnsV4Std, dnsV4Non, dnsV6Std, dnsV6Non} {
-		switch t {
-		case dnsV4Std:
-			config.dns[s.dnsHost+".A"] = rr4std
-		case dnsV4Non:
-			config.dns["nonstd."+s.dnsHost+".A"] = rr4non
-		case dnsV6Std:
-			config.dns[s.dnsHost+".AAAA"] = rr6std
-		case dnsV6Non:
-			config.dns["nonstd."+s.dnsHost+".AAAA"] = rr6non
-		}
+	for k, rrs := range records {
+		config.dns[k] = rrs
 	}
 
 	config.dnsmtx.Unlock()
 
 	if config.stats {
 		s.counts.mtx.RLock()
-		log.Printf("%s - DNS available: v4std: %v v4non: %v v6std: %v v6non: %v\n", s.name, len(rr4std), len(rr4non), len(rr6std), len(rr6non))
+		log.Printf("%s - DNS available: v4std: %v v4non: %v v6std: %v v6non: %v\n", s.name,
+			len(chosen[dnsV4Std]), 2*len(chosen[dnsV4Non]), len(chosen[dnsV6Std]), 2*len(chosen[dnsV6Non]))
 		log.Printf("%s - DNS counts: v4std: %v v4non: %v v6std: %v v6non: %v total: %v\n",
 			s.name,
 			s.counts.DNSCounts[dnsV4Std],
@@ -117,6 +168,20 @@ func updateDNS(s *dnsseeder) {
 	}
 }
 
+// newRR returns an A record or an AAAA record if v6 is true
+func newRR(name string, ip net.IP, ttl uint32, v6 bool) dns.RR {
+	if v6 == false {
+		r := new(dns.A)
+		r.Hdr = dns.RR_Header{Name: name + ".", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl}
+		r.A = ip
+		return r
+	}
+	r := new(dns.AAAA)
+	r.Hdr = dns.RR_Header{Name: name + ".", Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl}
+	r.AAAA = ip
+	return r
+}
+
 // handleDNS processes a DNS request from remote client and returns
 // a list of current ip addresses that the crawlers consider current.
 func handleDNS(w dns.ResponseWriter, r *dns.Msg) {
@@ -140,13 +205,22 @@ func handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 		qtype = "MX"
 	case dns.TypeNS:
 		qtype = "NS"
+	case dns.TypeSOA:
+		qtype = "SOA"
 	default:
 		qtype = "UNKNOWN"
 	}
 
+	// dns names are not case sensitive and the record keys are all lower case
+	qname := strings.ToLower(r.Question[0].Name)
+
 	config.dnsmtx.RLock()
 	// if the dns map does not have a key for the request it will return an empty slice
-	m.Answer = config.dns[r.Question[0].Name+qtype]
+	m.Answer = config.dns[qname+qtype]
+	// an empty answer for one of our names has the SOA in the authority section
+	if len(m.Answer) == 0 {
+		m.Ns = config.dns[strings.TrimPrefix(qname, "nonstd.")+"SOA"]
+	}
 	config.dnsmtx.RUnlock()
 
 	w.WriteMsg(m)
@@ -155,12 +229,12 @@ func handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 		log.Printf("debug - DNS response Type: standard  To IP: %s  Query Type: %s\n", w.RemoteAddr().String(), qtype)
 	}
 	// update the stats in a goroutine
-	go updateDNSCounts(r.Question[0].Name, qtype)
+	go updateDNSCounts(qname, qtype)
 }
 
-// serve starts the requested DNS server listening on the requested port
-func serve(net, port string) {
-	server := &dns.Server{Addr: ":" + port, Net: net, TsigSecret: nil}
+// serve starts the requested DNS server listening on the requested address
+func serve(net, addr string) {
+	server := &dns.Server{Addr: addr, Net: net, TsigSecret: nil}
 	if err := server.ListenAndServe(); err != nil {
 		log.Printf("Failed to setup the "+net+" server: %v\n", err)
 	}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// default SOA timers in seconds. The same as the bitcoin-seeder defaults
+const (
+	defSOARefresh = 604800
+	defSOARetry   = 86400
+	defSOAExpire  = 2592000
+	defSOAMinTTL  = 604800
+)
+
+// JDNSName is one dns name served for a network. TTL and SOA are optional
+type JDNSName struct {
+	Name string
+	TTL  uint32 `json:",omitempty"` // 0 to use the network TTL
+	SOA  *JSOA  `json:",omitempty"` // nil for no SOA record
+}
+
+// JSOA holds the settings for the SOA record of a dns name
+type JSOA struct {
+	NS      string // primary name server for the zone
+	Mbox    string // hostmaster mailbox in dns form, hostmaster.example.com
+	Serial  uint32 `json:",omitempty"` // 0 to use the time the records were last updated
+	Refresh uint32 `json:",omitempty"`
+	Retry   uint32 `json:",omitempty"`
+	Expire  uint32 `json:",omitempty"`
+	MinTTL  uint32 `json:",omitempty"`
+}
+
+// JDNSNames is the DNSName field of a network file. It can be a single name, a list of
+// names or a list of objects with the per name settings. All the names are served
+// from the same nodes
+type JDNSNames []JDNSName
+
+// newDNSNames returns the DNSName field for names with no per name settings
+func newDNSNames(names ...string) JDNSNames {
+	var dn JDNSNames
+	for _, n := range names {
+		dn = append(dn, JDNSName{Name: n})
+	}
+	return dn
+}
+
+// UnmarshalJSON accepts "name", ["name1", "name2"] or [{"Name": "name1", "TTL": 60}, "name2"]
+func (dn *JDNSNames) UnmarshalJSON(b []byte) error {
+
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*dn = nil
+		if one != "" {
+			*dn = newDNSNames(one)
+		}
+		return nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(b, &list); err != nil {
+		return fmt.Errorf("DNSName must be a name or a list of names")
+	}
+	names := JDNSNames{}
+	for _, raw := range list {
+		var jn JDNSName
+		if err := json.Unmarshal(raw, &jn.Name); err != nil {
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.DisallowUnknownFields()
+			if err = dec.Decode(&jn); err != nil {
+				return fmt.Errorf("Invalid DNSName entry %s - %v", raw, err)
+			}
+		}
+		names = append(names, jn)
+	}
+	*dn = names
+	return nil
+}
+
+// MarshalJSON writes names with no per name settings as strings and a single name
+// on its own so simple network files keep the same format
+func (dn JDNSNames) MarshalJSON() ([]byte, error) {
+
+	plain := func(jn JDNSName) bool { return jn.TTL == 0 && jn.SOA == nil }
+	if len(dn) == 0 {
+		return json.Marshal("")
+	}
+	if len(dn) == 1 && plain(dn[0]) {
+		return json.Marshal(dn[0].Name)
+	}
+	list := make([]interface{}, len(dn))
+	for i, jn := range dn {
+		if plain(jn) {
+			list[i] = jn.Name
+		} else {
+			list[i] = jn
+		}
+	}
+	return json.Marshal(list)
+}
+
+// dnsName is a dns name served by a seeder
+type dnsName struct {
+	host string   // dns name without the trailing dot
+	ttl  uint32   // ttl for this name or 0 to use the seeder ttl
+	soa  *dns.SOA // SOA record for this name or nil for none
+}
+
+// loadDNSNames checks the DNSName field and returns the names to serve
+func loadDNSNames(dn JDNSNames) ([]dnsName, error) {
+
+	if len(dn) == 0 {
+		return nil, fmt.Errorf("No DNS Hostname supplied")
+	}
+
+	var names []dnsName
+	seen := make(map[string]bool)
+	for _, jn := range dn {
+		// dns names are not case sensitive so they are stored and matched in lower case
+		host := strings.ToLower(strings.TrimSuffix(jn.Name, "."))
+		if host == "" {
+			return nil, fmt.Errorf("Empty DNS Hostname supplied")
+		}
+		if seen[host] {
+			return nil, fmt.Errorf("Duplicate DNS Hostname %s", host)
+		}
+		seen[host] = true
+
+		n := dnsName{host: host, ttl: jn.TTL}
+		if n.ttl != 0 && n.ttl < 60 {
+			n.ttl = 60
+		}
+		if jn.SOA != nil {
+			if jn.SOA.NS == "" || jn.SOA.Mbox == "" {
+				return nil, fmt.Errorf("SOA for %s needs NS and Mbox", host)
+			}
+			n.soa = &dns.SOA{
+				Hdr:     dns.RR_Header{Name: dns.Fqdn(host), Rrtype: dns.TypeSOA, Class: dns.ClassINET},
+				Ns:      dns.Fqdn(jn.SOA.NS),
+				Mbox:    dns.Fqdn(jn.SOA.Mbox),
+				Serial:  jn.SOA.Serial,
+				Refresh: orDefault(jn.SOA.Refresh, defSOARefresh),
+				Retry:   orDefault(jn.SOA.Retry, defSOARetry),
+				Expire:  orDefault(jn.SOA.Expire, defSOAExpire),
+				Minttl:  orDefault(jn.SOA.MinTTL, defSOAMinTTL),
+			}
+		}
+		names = append(names, n)
+	}
+	return names, nil
+}
+
+// orDefault returns v or def if v is 0
+func orDefault(v, def uint32) uint32 {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// ttlFor returns the ttl to use for the records of a name
+func (s *dnsseeder) ttlFor(n dnsName) uint32 {
+	if n.ttl != 0 {
+		return n.ttl
+	}
+	return s.ttl
+}
+
+// soaRR returns the SOA record to serve for a name. A serial of 0 in the network file
+// uses the time the records were updated so secondaries see each change
+func (s *dnsseeder) soaRR(n dnsName, updated time.Time) []dns.RR {
+	if n.soa == nil {
+		return nil
+	}
+	soa := *n.soa
+	soa.Hdr.Ttl = s.ttlFor(n)
+	if soa.Serial == 0 {
+		soa.Serial = uint32(updated.Unix())
+	}
+	return []dns.RR{&soa}
+}
+
+// servesName returns the dns name the seeder serves for a query name with the
+// trailing dot or false if it does not serve it. The nonstd. prefix is removed
+func (s *dnsseeder) servesName(qname string) (string, bool) {
+	qname = strings.TrimPrefix(qname, "nonstd.")
+	for _, n := range s.dnsNames {
+		if qname == n.host+"." {
+			return n.host, true
+		}
+	}
+	return "", false
+}
+
+// diffDNSNames returns the hosts in old that are not in new and the names in new that
+// were added or have different settings so a reload only touches the names it changes
+func diffDNSNames(old, new []dnsName) ([]string, []dnsName) {
+
+	prev := make(map[string]dnsName)
+	for _, n := range old {
+		prev[n.host] = n
+	}
+
+	var changed []dnsName
+	for _, n := range new {
+		o, ok := prev[n.host]
+		if ok == false || o.ttl != n.ttl || reflect.DeepEqual(o.soa, n.soa) == false {
+			changed = append(changed, n)
+		}
+		delete(prev, n.host)
+	}
+
+	var removed []string
+	for _, n := range old {
+		if _, ok := prev[n.host]; ok {
+			removed = append(removed, n.host)
+		}
+	}
+	return removed, changed
+}
+
+// names2str returns the dns names and the number of requests for each
+func (s *dnsseeder) names2str() string {
+	var parts []string
+	for _, n := range s.dnsNames {
+		parts = append(parts, fmt.Sprintf("%s: %v", n.host, s.counts.NameCounts[n.host]))
+	}
+	return strings.Join(parts, " ")
+}
+
+/*
+
+ */
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/miekg/dns"
+)
+
+func TestDNSNamesJSON(t *testing.T) {
+
+	var tests = []struct {
+		j     string
+		names []string
+		ttl   uint32
+		out   string
+	}{
+		{`"seed.a.test"`, []string{"seed.a.test"}, 0, `"seed.a.test"`},
+		{`""`, nil, 0, `""`},
+		{`["seed.a.test", "seed.b.test"]`, []string{"seed.a.test", "seed.b.test"}, 0, `["seed.a.test","seed.b.test"]`},
+		{`[{"Name": "seed.a.test", "TTL": 120}, "seed.b.test"]`, []string{"seed.a.test", "seed.b.test"}, 120,
+			`[{"Name":"seed.a.test","TTL":120},"seed.b.test"]`},
+		{`[{"Name": "seed.a.test"}]`, []string{"seed.a.test"}, 0, `"seed.a.test"`},
+	}
+	for _, tt := range tests {
+		var dn JDNSNames
+		if err := json.Unmarshal([]byte(tt.j), &dn); err != nil {
+			t.Errorf("%s: %v", tt.j, err)
+			continue
+		}
+		if len(dn) != len(tt.names) {
+			t.Errorf("%s decoded as %+v", tt.j, dn)
+			continue
+		}
+		for i, n := range tt.names {
+			if dn[i].Name != n {
+				t.Errorf("%s name %v: %s expected: %s", tt.j, i, dn[i].Name, n)
+			}
+		}
+		if len(dn) > 0 && dn[0].TTL != tt.ttl {
+			t.Errorf("%s ttl: %v expected: %v", tt.j, dn[0].TTL, tt.ttl)
+		}
+		if out, err := json.Marshal(dn); err != nil || string(out) != tt.out {
+			t.Errorf("%s encoded as %s expected: %s", tt.j, out, tt.out)
+		}
+	}
+
+	for _, bad := range []string{`42`, `[42]`, `[{"Name": "seed.a.test", "Colour": "blue"}]`, `{"Name": "seed.a.test"}`} {
+		var dn JDNSNames
+		if err := json.Unmarshal([]byte(bad), &dn); err == nil {
+			t.Errorf("bad DNSName accepted: %s", bad)
+		}
+	}
+}
+
+func TestLoadDNSNames(t *testing.T) {
+
+	names, err := loadDNSNames(JDNSNames{
+		{Name: "seed.a.test.", TTL: 30},
+		{Name: "seed.b.test", SOA: &JSOA{NS: "ns.b.test", Mbox: "hostmaster.b.test", Retry: 600}},
+	})
+	if err != nil {
+		t.Fatalf("unable to load dns names: %v", err)
+	}
+	if names[0].host != "seed.a.test" || names[0].ttl != 60 || names[0].soa != nil {
+		t.Errorf("first name: %+v", names[0])
+	}
+	soa := names[1].soa
+	if soa == nil || soa.Ns != "ns.b.test." || soa.Mbox != "hostmaster.b.test." || soa.Retry != 600 || soa.Refresh != defSOARefresh {
+		t.Errorf("soa: %v", soa)
+	}
+
+	// names are stored in lower case as dns names are not case sensitive
+	if names, err = loadDNSNames(newDNSNames("Seed.Example.COM")); err != nil || names[0].host != "seed.example.com" {
+		t.Errorf("mixed case name loaded as %+v %v", names, err)
+	}
+
+	var bad = []JDNSNames{
+		nil,
+		newDNSNames(""),
+		newDNSNames("seed.a.test", "SEED.A.TEST"),
+		{{Name: "seed.a.test", SOA: &JSOA{NS: "ns.a.test"}}},
+	}
+	for _, dn := range bad {
+		if _, err = loadDNSNames(dn); err == nil {
+			t.Errorf("bad dns names accepted: %+v", dn)
+		}
+	}
+}
+
+// dnsRecorder keeps the reply written by handleDNS
+type dnsRecorder struct {
+	dns.ResponseWriter
+	msg *dns.Msg
+}
+
+func (r *dnsRecorder) WriteMsg(m *dns.Msg) error { r.msg = m; return nil }
+func (r *dnsRecorder) RemoteAddr() net.Addr      { return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+
+func TestServeDNSNames(t *testing.T) {
+
+	jnw := simNetwork()
+	jnw.TTL = 600
+	jnw.AllowPrivate = true
+	jnw.DNSName = JDNSNames{
+		{Name: "seed.a.test"},
+		{Name: "seed.b.test", TTL: 120, SOA: &JSOA{NS: "ns.b.test", Mbox: "hostmaster.b.test", Serial: 7}},
+	}
+	s, err := initNetwork(jnw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		nd := s.addNode(wire.NewNetAddressIPPort(net.ParseIP(fmt.Sprintf("20.%d.0.1", i)), 8333, 0), "")
+		nd.status = statusCG
+		nd.lastConnect = time.Now()
+	}
+
+	config.dns = make(map[string][]dns.RR)
+	config.seeders = map[string]*dnsseeder{s.name: s}
+	defer func() {
+		config.smtx.Lock()
+		config.seeders = nil
+		config.smtx.Unlock()
+	}()
+	updateDNS(s)
+
+	// both names are served from the same nodes with their own ttl
+	a, b := config.dns["seed.a.test.A"], config.dns["seed.b.test.A"]
+	if len(a) != 3 || len(b) != 3 {
+		t.Fatalf("records for seed.a.test: %v seed.b.test: %v expected 3", len(a), len(b))
+	}
+	for i := range a {
+		if a[i].(*dns.A).A.Equal(b[i].(*dns.A).A) == false {
+			t.Errorf("names served different nodes: %v %v", a[i], b[i])
+		}
+	}
+	if a[0].Header().Ttl != 600 || b[0].Header().Ttl != 120 || b[0].Header().Name != "seed.b.test." {
+		t.Errorf("record headers: %v %v", a[0].Header(), b[0].Header())
+	}
+
+	// requests are counted for each name. handleDNS counts in a goroutine so this is
+	// checked before any queries
+	updateDNSCounts("seed.a.test.", "A")
+	updateDNSCounts("seed.b.test.", "A")
+	updateDNSCounts("nonstd.seed.b.test.", "AAAA")
+	if s.counts.NameCounts["seed.a.test"] != 1 || s.counts.NameCounts["seed.b.test"] != 2 || s.counts.DNSCounts[dnsV4Std] != 2 {
+		t.Errorf("name counts: %v dns counts: %v", s.counts.NameCounts, s.counts.DNSCounts)
+	}
+	if str := s.names2str(); str != "seed.a.test: 1 seed.b.test: 2" {
+		t.Errorf("names2str: %s", str)
+	}
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(name, qtype)
+		w := &dnsRecorder{}
+		handleDNS(w, req)
+		return w.msg
+	}
+
+	m := query("seed.b.test.", dns.TypeSOA)
+	if len(m.Answer) != 1 {
+		t.Fatalf("SOA answer: %v", m.Answer)
+	}
+	if soa := m.Answer[0].(*dns.SOA); soa.Serial != 7 || soa.Ns != "ns.b.test." || soa.Hdr.Ttl != 120 {
+		t.Errorf("SOA: %v", soa)
+	}
+	if m = query("seed.a.test.", dns.TypeSOA); len(m.Answer) != 0 || len(m.Ns) != 0 {
+		t.Errorf("SOA served for a name without one: %v", m)
+	}
+	// the query name is matched without case
+	if m = query("Seed.B.TEST.", dns.TypeSOA); len(m.Answer) != 1 {
+		t.Errorf("mixed case SOA answer: %v", m.Answer)
+	}
+	// no AAAA records so the SOA is in the authority section
+	if m = query("nonstd.seed.b.test.", dns.TypeAAAA); len(m.Answer) != 0 || len(m.Ns) != 1 {
+		t.Errorf("empty answer authority: %v", m.Ns)
+	}
+
+	// wait for handleDNS to count the 4 queries so it is done with config.seeders
+	for i := 0; i < 100; i++ {
+		s.counts.mtx.RLock()
+		n := s.counts.NameCounts["seed.a.test"] + s.counts.NameCounts["seed.b.test"]
+		s.counts.mtx.RUnlock()
+		if n == 7 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the dns page shows the records of every name
+	w := httptest.NewRecorder()
+	dnsWebHandler(w, httptest.NewRequest("GET", "/dns?s="+s.name, nil))
+	for _, want := range []string{"records for seed.a.test", "records for seed.b.test", "hostmaster.b.test."} {
+		if strings.Contains(w.Body.String(), want) == false {
+			t.Errorf("dns page does not show %s", want)
+		}
+	}
+
+	// a name used by another network is a duplicate
+	other := simNetwork()
+	other.Name = "Other"
+	other.ID = "0x0709110c"
+	other.DNSName = newDNSNames("seed.c.test", "SEED.B.test")
+	o, err := initNetwork(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dups := duplicateSeeder(o, config.seeders); len(dups) != 1 {
+		t.Errorf("duplicate names: %v", dups)
+	}
+
+	// a reload that only drops the SOA of one name keeps the records of every name
+	jnw.DNSName = JDNSNames{{Name: "seed.a.test"}, {Name: "seed.b.test", TTL: 120}}
+	ns, err := initNetwork(jnw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed := s.update(ns); len(changed) != 1 || changed[0] != "DNSName" {
+		t.Errorf("reload changed: %v", changed)
+	}
+	if len(config.dns["seed.a.test.A"]) != 3 || len(config.dns["seed.b.test.A"]) != 3 {
+		t.Errorf("records of kept names removed by a reload")
+	}
+	if _, ok := config.dns["seed.b.test.SOA"]; ok {
+		t.Errorf("SOA removed from a name is still served")
+	}
+
+	// names in a different order are not a change
+	jnw.DNSName = JDNSNames{{Name: "seed.b.test", TTL: 120}, {Name: "seed.a.test"}}
+	if ns, err = initNetwork(jnw); err != nil {
+		t.Fatal(err)
+	}
+	if changed := s.update(ns); len(changed) != 0 {
+		t.Errorf("reload of the same names changed: %v", changed)
+	}
+
+	// a reload that drops a name removes its records
+	jnw.DNSName = newDNSNames("seed.a.test")
+	if ns, err = initNetwork(jnw); err != nil {
+		t.Fatal(err)
+	}
+	if changed := s.update(ns); len(changed) != 1 || changed[0] != "DNSName" {
+		t.Errorf("reload changed: %v", changed)
+	}
+	if _, ok := config.dns["seed.b.test.A"]; ok {
+		t.Errorf("records for a removed name are still served")
+	}
+}
+
+/*
+
+ */
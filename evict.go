@@ -0,0 +1,87 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	// what to do with a new address when theList is full
+	evictWorst  = "evict"  // remove the worst node to make room
+	evictRefuse = "refuse" // refuse the new address
+
+	evictCacheAge = 60 // seconds before the eviction candidates are sorted again
+)
+
+// evictRank orders the node status by how little a node is worth keeping
+var evictRank = [maxStatusTypes]int{statusRG: 1, statusCG: 0, statusWG: 2, statusNG: 3}
+
+// evictable returns true if the node is worth less than a new address we have not tried.
+// Good nodes, nodes being crawled and nodes we have not tried yet are never evicted
+func evictable(nd *node) bool {
+	if nd.crawlActive || nd.status == statusCG {
+		return false
+	}
+	return nd.status != statusRG || nd.connectFails > 0
+}
+
+// evictWorse returns true if node a should be evicted before node b. Nodes are ordered
+// by status then rating and then the node we connected to longest ago goes first
+func evictWorse(a, b *node) bool {
+	if evictRank[a.status] != evictRank[b.status] {
+		return evictRank[a.status] > evictRank[b.status]
+	}
+	if a.rating != b.rating {
+		return a.rating > b.rating
+	}
+	return a.lastConnect.Before(b.lastConnect)
+}
+
+// evictNode removes the worst node in theList to make room for a new address.
+// It returns false if there is no node worth less than a new address
+func (s *dnsseeder) evictNode() bool {
+
+	// sorting theList for every new address is too slow so work through a sorted
+	// list of candidates and only rebuild it when it is used up or old
+	if len(s.evictCands) == 0 || time.Since(s.evictSorted) > time.Second*evictCacheAge {
+		s.evictCands = s.evictCands[:0]
+		for _, nd := range s.theList {
+			if evictable(nd) {
+				s.evictCands = append(s.evictCands, nd)
+			}
+		}
+		sort.Slice(s.evictCands, func(i, j int) bool { return evictWorse(s.evictCands[i], s.evictCands[j]) })
+		s.evictSorted = time.Now()
+	}
+
+	for len(s.evictCands) > 0 {
+		nd := s.evictCands[0]
+		s.evictCands = s.evictCands[1:]
+
+		// the node may have been removed or crawled since the list was sorted
+		if s.theList[nd.addr] != nd || evictable(nd) == false {
+			continue
+		}
+
+		if config.debug {
+			log.Printf("%s - debug - evicting node %s status: %s rating: %v to make room\n", s.name, nd.addr, status2str(nd.status), nd.rating)
+		}
+		s.removeNode(nd.addr)
+		s.counts.mtx.Lock()
+		s.counts.Evicted++
+		s.counts.mtx.Unlock()
+		return true
+	}
+	return false
+}
+
+// wantAddrs returns true if a crawl should ask the node for addresses. With the evict
+// policy new addresses can always replace worse nodes so they are always wanted
+func (s *dnsseeder) wantAddrs() bool {
+	return s.evictPolicy == evictWorst || len(s.theList) <= s.maxSize
+}
+
+/*
+
+ */
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/gombadi/dnsseeder/simpeer"
+)
+
+func TestEvictNode(t *testing.T) {
+
+	s := &dnsseeder{
+		port:        8333,
+		maxSize:     3,
+		evictPolicy: evictWorst,
+	}
+	s.theList = make(map[nodeAddr]*node)
+
+	addIP := func(ip string) *node {
+		return s.addNode(wire.NewNetAddressIPPort(net.ParseIP(ip), 8333, 0), "")
+	}
+
+	// fill theList with one node at each status
+	var tests = []struct {
+		ip     string
+		status uint32
+		rating uint32
+		fails  uint32
+	}{
+		{"1.0.0.1", statusCG, 0, 0},
+		{"2.0.0.1", statusNG, 110, 60},
+		{"3.0.0.1", statusWG, 65, 5},
+		{"4.0.0.1", statusRG, 25, 1},
+	}
+	for _, tt := range tests {
+		nd := addIP(tt.ip)
+		if nd == nil {
+			t.Fatalf("unable to add node %s", tt.ip)
+		}
+		s.setStatus(nd, tt.status)
+		nd.rating = tt.rating
+		nd.connectFails = tt.fails
+		nd.lastConnect = time.Now().Add(-time.Hour)
+	}
+
+	// new addresses replace the worst nodes in order NG, WG then failed RG
+	for i, evicted := range []string{"2.0.0.1:8333", "3.0.0.1:8333", "4.0.0.1:8333"} {
+		if addIP(fmt.Sprintf("9.0.0.%d", i+1)) == nil {
+			t.Errorf("new address %v refused with evictable nodes in theList", i+1)
+		}
+		if _, ok := s.theList[mustAddr(evicted)]; ok {
+			t.Errorf("node %s not evicted", evicted)
+		}
+	}
+
+	// only the CG node and untried nodes are left so nothing can be evicted
+	if addIP("9.0.0.9") != nil {
+		t.Errorf("new address added by evicting a good or untried node")
+	}
+	if _, ok := s.theList[mustAddr("1.0.0.1:8333")]; ok == false {
+		t.Errorf("statusCG node was evicted")
+	}
+	if s.counts.Evicted != 3 {
+		t.Errorf("evicted count: %v expected: 3", s.counts.Evicted)
+	}
+
+	// a node that is being crawled is skipped even if it was sorted as a candidate
+	nd := s.theList[mustAddr("9.0.0.1:8333")]
+	nd.connectFails = 1
+	s.evictCands = []*node{nd}
+	s.evictSorted = time.Now()
+	nd.crawlActive = true
+	if s.evictNode() == true {
+		t.Errorf("node evicted while being crawled")
+	}
+
+	// the refuse policy never evicts
+	nd.crawlActive = false
+	s.evictPolicy = evictRefuse
+	if addIP("9.0.0.10") != nil {
+		t.Errorf("new address added with the refuse policy")
+	}
+}
+
+func TestFullListCrawl(t *testing.T) {
+
+	s := newSimSeeder(t, simpeer.NewNetwork(), simNetwork())
+	s.maxSize = 1
+	for _, ip := range []string{"1.0.0.1", "2.0.0.1"} {
+		s.addNa(simAddr(ip, 8333), "")
+	}
+
+	// a full list still asks for addresses when worse nodes can be evicted
+	if s.wantAddrs() == false {
+		t.Errorf("full list with the evict policy does not ask for addresses")
+	}
+	s.evictPolicy = evictRefuse
+	if s.wantAddrs() == true {
+		t.Errorf("full list with the refuse policy asks for addresses")
+	}
+
+	// a failed untried node is rated as normal rather than dropped to statusNG
+	k := mustAddr("1.0.0.1:8333")
+	acquireCrawl()
+	s.activeCrawls++
+	s.theList[k].crawlActive = true
+	s.processResult(&result{node: k, msg: &crawlError{"test", errors.New("connection refused")}})
+	if nd := s.theList[k]; nd.status != statusRG || nd.rating != 25 {
+		t.Errorf("failed node in a full list: %s:%v expected: statusRG:25", status2str(nd.status), nd.rating)
+	}
+}
+
+/*
+
+ */
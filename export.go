@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// exporter writes a list of nodes in one output format
+type exporter interface {
+	contentType() string
+	write(w io.Writer, s *dnsseeder, nodes []exportNode, opts map[string]string) error
+}
+
+// exporters holds the available output formats by name
+var exporters = map[string]exporter{
+	"txt":         txtExporter{},
+	"json":        jsonExporter{},
+	"csv":         csvExporter{},
+	"chainparams": chainParamsExporter{},
+}
+
+// exportNode is a copy of the node details that are exported so the node list
+// does not need to be locked while the output is written
+type exportNode struct {
+	Address     string
+	IP          string
+	Port        uint16
+	Status      string
+	Good        bool
+	LastSuccess time.Time
+	Uptime2H    float64
+	Uptime8H    float64
+	Uptime1D    float64
+	Uptime7D    float64
+	Uptime30D   float64
+	Blocks      int32
+	Services    uint64
+	Version     int32
+	UserAgent   string
+	Netgroup    string
+	addr        nodeAddr
+	score       float64
+}
+
+// exportFilter selects the nodes to export
+type exportFilter struct {
+	status    []uint32         // only nodes with these statuses or all nodes if empty
+	services  wire.ServiceFlag // service bits a node must have
+	minUptime float64          // min 30 day uptime percentage
+	perGroup  int              // max nodes from one netgroup or 0 for no limit
+}
+
+// exportOptions are the option names shared by the web interface and the export subcommand
+var exportOptions = []string{"format", "status", "services", "uptime", "pergroup", "name"}
+
+// parseExportFilter builds a filter from the export options
+func parseExportFilter(opts map[string]string) (exportFilter, error) {
+
+	var f exportFilter
+	if v := opts["status"]; v != "" {
+		for _, st := range strings.Split(v, ",") {
+			found := false
+			for i := uint32(0); i < maxStatusTypes; i++ {
+				if strings.EqualFold(strings.TrimSpace(st), status2str(i)[len("status"):]) {
+					f.status = append(f.status, i)
+					found = true
+				}
+			}
+			if found == false {
+				return f, fmt.Errorf("Invalid status %s. Must be RG, CG, WG or NG", st)
+			}
+		}
+	}
+	if v := opts["services"]; v != "" {
+		sv, err := strconv.ParseUint(v, 0, 64)
+		if err != nil {
+			return f, fmt.Errorf("Invalid services %s", v)
+		}
+		f.services = wire.ServiceFlag(sv)
+	}
+	if v := opts["uptime"]; v != "" {
+		u, err := strconv.ParseFloat(v, 64)
+		if err != nil || u < 0 || u > 100 {
+			return f, fmt.Errorf("Invalid uptime %s. Must be a percentage", v)
+		}
+		f.minUptime = u
+	}
+	if v := opts["pergroup"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("Invalid pergroup %s", v)
+		}
+		f.perGroup = n
+	}
+	return f, nil
+}
+
+// match returns true if the node passes the status, services and uptime filters
+func (f exportFilter) match(nd *node) bool {
+	if len(f.status) > 0 {
+		found := false
+		for _, st := range f.status {
+			if nd.status == st {
+				found = true
+			}
+		}
+		if found == false {
+			return false
+		}
+	}
+	if nd.services&f.services != f.services {
+		return false
+	}
+	return nd.uptime(stat30D) >= f.minUptime
+}
+
+// exportNodes returns the nodes that pass the filter with the best nodes first.
+// The netgroup cap keeps the best nodes from each netgroup
+func (s *dnsseeder) exportNodes(f exportFilter) []exportNode {
+
+	s.mtx.RLock()
+	var ens []exportNode
+	for k, nd := range s.theList {
+		if f.match(nd) == false {
+			continue
+		}
+		ens = append(ens, exportNode{
+			Address:     k.String(),
+			IP:          k.IP().String(),
+			Port:        k.Port(),
+			Status:      status2str(nd.status),
+			Good:        nd.status == statusCG,
+			LastSuccess: nd.lastConnect,
+			Uptime2H:    nd.uptime(stat2H),
+			Uptime8H:    nd.uptime(stat8H),
+			Uptime1D:    nd.uptime(stat1D),
+			Uptime7D:    nd.uptime(stat7D),
+			Uptime30D:   nd.uptime(stat30D),
+			Blocks:      nd.lastBlock,
+			Services:    uint64(nd.services),
+			Version:     nd.version,
+			UserAgent:   nd.strVersion,
+			Netgroup:    nd.group,
+			addr:        k,
+			score:       nd.score(),
+		})
+	}
+	s.mtx.RUnlock()
+
+	sort.Slice(ens, func(i, j int) bool {
+		if ens[i].score != ens[j].score {
+			return ens[i].score > ens[j].score
+		}
+		return ens[i].Address < ens[j].Address
+	})
+
+	if f.perGroup > 0 {
+		groups := make(map[string]int)
+		kept := ens[:0]
+		for _, en := range ens {
+			if groups[en.Netgroup] < f.perGroup {
+				groups[en.Netgroup]++
+				kept = append(kept, en)
+			}
+		}
+		ens = kept
+	}
+	return ens
+}
+
+// export writes the nodes that pass the filter in the format named in the options
+func (s *dnsseeder) export(w io.Writer, opts map[string]string) error {
+
+	format := opts["format"]
+	if format == "" {
+		format = "txt"
+	}
+	ex, ok := exporters[format]
+	if ok == false {
+		return fmt.Errorf("Unknown export format %s", format)
+	}
+	f, err := parseExportFilter(opts)
+	if err != nil {
+		return err
+	}
+	return ex.write(w, s, s.exportNodes(f), opts)
+}
+
+// txtExporter writes the seeds.txt format read by Bitcoin Core's contrib/seeds/makeseeds.py.
+// It splits each line on white space and expects the quoted user agent last
+type txtExporter struct{}
+
+func (txtExporter) contentType() string { return "text/plain" }
+
+func (txtExporter) write(w io.Writer, s *dnsseeder, nodes []exportNode, opts map[string]string) error {
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "# address\tgood\tlastSuccess\t%%(2h)\t%%(8h)\t%%(1d)\t%%(7d)\t%%(30d)\tblocks\tsvcs\tversion\tagent\n")
+	for _, en := range nodes {
+		good := 0
+		if en.Good {
+			good = 1
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f%%\t%.2f%%\t%.2f%%\t%.2f%%\t%.2f%%\t%d\t%08x\t%d\t%q\n",
+			en.Address, good, en.LastSuccess.Unix(), en.Uptime2H, en.Uptime8H, en.Uptime1D, en.Uptime7D, en.Uptime30D,
+			en.Blocks, en.Services, en.Version, en.UserAgent)
+	}
+	return tw.Flush()
+}
+
+// jsonExporter writes the nodes as a json array
+type jsonExporter struct{}
+
+func (jsonExporter) contentType() string { return "application/json" }
+
+func (jsonExporter) write(w io.Writer, s *dnsseeder, nodes []exportNode, opts map[string]string) error {
+	if nodes == nil {
+		nodes = []exportNode{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(nodes)
+}
+
+// csvExporter writes the nodes as csv with a header line
+type csvExporter struct{}
+
+func (csvExporter) contentType() string { return "text/csv" }
+
+func (csvExporter) write(w io.Writer, s *dnsseeder, nodes []exportNode, opts map[string]string) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"address", "status", "good", "lastSuccess", "uptime2h", "uptime8h", "uptime1d", "uptime7d", "uptime30d",
+		"blocks", "services", "version", "agent", "netgroup"})
+	pc := func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+	for _, en := range nodes {
+		cw.Write([]string{en.Address, en.Status, strconv.FormatBool(en.Good), strconv.FormatInt(en.LastSuccess.Unix(), 10),
+			pc(en.Uptime2H), pc(en.Uptime8H), pc(en.Uptime1D), pc(en.Uptime7D), pc(en.Uptime30D),
+			strconv.Itoa(int(en.Blocks)), fmt.Sprintf("%08x", en.Services), strconv.Itoa(int(en.Version)), en.UserAgent, en.Netgroup})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// chainParamsExporter writes a Bitcoin Core chainparamsseeds.h array with each address
+// serialized in the BIP155 form used by contrib/seeds/generate-seeds.py
+type chainParamsExporter struct{}
+
+func (chainParamsExporter) contentType() string { return "text/plain" }
+
+func (chainParamsExporter) write(w io.Writer, s *dnsseeder, nodes []exportNode, opts map[string]string) error {
+
+	name := opts["name"]
+	if name == "" {
+		name = "chainparams_seed_main"
+	}
+	fmt.Fprintf(w, "// List of fixed seed nodes for %s from dnsseeder\n", s.name)
+	fmt.Fprintf(w, "// Each entry is a BIP155 serialized (networkID, addr, port) tuple\n")
+	fmt.Fprintf(w, "static const uint8_t %s[] = {\n", name)
+	for _, en := range nodes {
+		b := bip155Serialize(en.addr)
+		hex := make([]string, len(b))
+		for i, c := range b {
+			hex[i] = fmt.Sprintf("0x%02x", c)
+		}
+		fmt.Fprintf(w, "    %s, // %s\n", strings.Join(hex, ","), en.Address)
+	}
+	_, err := fmt.Fprintf(w, "};\n")
+	return err
+}
+
+// bip155Serialize returns the network id, address length, address and big endian port
+func bip155Serialize(a nodeAddr) []byte {
+	var b bytes.Buffer
+	ip := a.IP()
+	if a.is4() {
+		b.WriteByte(bip155IPv4)
+	} else {
+		b.WriteByte(bip155IPv6)
+	}
+	wire.WriteVarInt(&b, 0, uint64(len(ip)))
+	b.Write(ip)
+	b.WriteByte(byte(a.Port() >> 8))
+	b.WriteByte(byte(a.Port()))
+	return b.Bytes()
+}
+
+// runExport is the export subcommand. It writes the nodes from a snapshot in the data
+// directory so a seeder does not need to be running
+func runExport(args []string) error {
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	nf := fs.String("netfile", "", "List of json config files to load")
+	network := fs.String("network", "", "Name of the network to export. Not needed if only one is loaded")
+	out := fs.String("o", "", "File to write. No file & written to stdout")
+	fs.StringVar(&config.datadir, "datadir", "", "Directory with the node snapshots")
+	opts := make(map[string]*string)
+	for _, o := range exportOptions {
+		opts[o] = fs.String(o, "", "")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s export -netfile <file[,file2]> -datadir <dir> [-network name] [-format txt|json|csv|chainparams]\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "    [-status RG,CG,WG,NG] [-services bits] [-uptime percent] [-pergroup n] [-name array name] [-o file]\n")
+	}
+	fs.Parse(args)
+
+	if *nf == "" || config.datadir == "" {
+		fs.Usage()
+		return fmt.Errorf("Error - export needs -netfile and -datadir")
+	}
+
+	seeders, order, err := loadNetworks(strings.Split(*nf, ","))
+	if err != nil {
+		return err
+	}
+	if *network == "" {
+		if len(order) != 1 {
+			return fmt.Errorf("Error - use -network to say which network to export")
+		}
+		*network = order[0]
+	}
+	s := seeders[*network]
+	if s == nil {
+		return fmt.Errorf("Error - no network called %s", *network)
+	}
+	if _, err = s.loadNodes(); err != nil {
+		return err
+	}
+
+	eo := make(map[string]string)
+	for k, v := range opts {
+		eo[k] = *v
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("Error creating %s - %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	return s.export(w, eo)
+}
+
+/*
+
+ */
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestExport(t *testing.T) {
+
+	s := &dnsseeder{name: "SimNet", port: 8333, maxSize: 1250}
+	s.theList = make(map[nodeAddr]*node)
+
+	var tests = []struct {
+		ip       string
+		status   uint32
+		services wire.ServiceFlag
+		uptime   float64
+	}{
+		{"1.2.3.4", statusCG, wire.SFNodeNetwork | wire.SFNodeWitness, 0.9},
+		{"1.2.3.5", statusCG, wire.SFNodeNetwork | wire.SFNodeWitness, 0.8},
+		{"5.6.7.8", statusCG, wire.SFNodeNetwork, 0.9},
+		{"2a01:4f8::1", statusCG, wire.SFNodeNetwork | wire.SFNodeWitness, 0.3},
+		{"9.9.9.9", statusWG, wire.SFNodeNetwork | wire.SFNodeWitness, 0.9},
+	}
+	for _, tt := range tests {
+		nd := s.addNode(wire.NewNetAddressIPPort(net.ParseIP(tt.ip), 8333, 0), "")
+		if nd == nil {
+			t.Fatalf("unable to add node %s", tt.ip)
+		}
+		s.setStatus(nd, tt.status)
+		nd.services = tt.services
+		nd.strVersion = "/Satoshi:0.20.1/"
+		for i := range nd.stats {
+			nd.stats[i] = addrStat{Weight: 1, Count: 1, Reliability: tt.uptime}
+		}
+	}
+
+	var tf = []struct {
+		opts  map[string]string
+		nodes []string
+	}{
+		{map[string]string{}, []string{"1.2.3.4:8333", "5.6.7.8:8333", "9.9.9.9:8333", "1.2.3.5:8333", "[2a01:4f8::1]:8333"}},
+		{map[string]string{"status": "CG"}, []string{"1.2.3.4:8333", "5.6.7.8:8333", "1.2.3.5:8333", "[2a01:4f8::1]:8333"}},
+		{map[string]string{"status": "cg,WG", "services": "0x9"}, []string{"1.2.3.4:8333", "9.9.9.9:8333", "1.2.3.5:8333", "[2a01:4f8::1]:8333"}},
+		{map[string]string{"status": "CG", "uptime": "50"}, []string{"1.2.3.4:8333", "5.6.7.8:8333", "1.2.3.5:8333"}},
+		{map[string]string{"status": "CG", "pergroup": "1"}, []string{"1.2.3.4:8333", "5.6.7.8:8333", "[2a01:4f8::1]:8333"}},
+	}
+	for _, tt := range tf {
+		f, err := parseExportFilter(tt.opts)
+		if err != nil {
+			t.Fatalf("filter %v: %v", tt.opts, err)
+		}
+		var got []string
+		for _, en := range s.exportNodes(f) {
+			got = append(got, en.Address)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.nodes, " ") {
+			t.Errorf("filter %v nodes: %v expected: %v", tt.opts, got, tt.nodes)
+		}
+	}
+
+	for _, bad := range []map[string]string{{"status": "XX"}, {"services": "abc"}, {"uptime": "101"}, {"pergroup": "-1"}} {
+		if _, err := parseExportFilter(bad); err == nil {
+			t.Errorf("invalid filter accepted: %v", bad)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := s.export(&b, map[string]string{"format": "xml"}); err == nil {
+		t.Errorf("unknown format accepted")
+	}
+
+	// seeds.txt can be read back by our own importer
+	b.Reset()
+	if err := s.export(&b, map[string]string{}); err != nil {
+		t.Fatalf("txt export: %v", err)
+	}
+	if addrs, err := readSeedList(&b, ""); err != nil || len(addrs) != 5 {
+		t.Errorf("txt export read back %v addresses: %v", len(addrs), err)
+	}
+
+	b.Reset()
+	if err := s.export(&b, map[string]string{"format": "json", "status": "WG"}); err != nil {
+		t.Fatalf("json export: %v", err)
+	}
+	var jn []exportNode
+	if err := json.Unmarshal(b.Bytes(), &jn); err != nil || len(jn) != 1 || jn[0].Address != "9.9.9.9:8333" || jn[0].Uptime30D != 90 {
+		t.Errorf("json export: %v %+v", err, jn)
+	}
+
+	b.Reset()
+	if err := s.export(&b, map[string]string{"format": "csv"}); err != nil {
+		t.Fatalf("csv export: %v", err)
+	}
+	if rows, err := csv.NewReader(&b).ReadAll(); err != nil || len(rows) != 6 || rows[1][0] != "1.2.3.4:8333" || rows[1][12] != "/Satoshi:0.20.1/" {
+		t.Errorf("csv export: %v %v", err, rows)
+	}
+
+	b.Reset()
+	if err := s.export(&b, map[string]string{"format": "chainparams", "status": "CG", "uptime": "50", "pergroup": "1", "name": "chainparams_seed_test"}); err != nil {
+		t.Fatalf("chainparams export: %v", err)
+	}
+	out := b.String()
+	if strings.Contains(out, "static const uint8_t chainparams_seed_test[] = {") == false ||
+		strings.Contains(out, "    0x01,0x04,0x01,0x02,0x03,0x04,0x20,0x8d, // 1.2.3.4:8333\n") == false {
+		t.Errorf("chainparams export:\n%s", out)
+	}
+
+	// ipv6 addresses use BIP155 network id 2 and 16 bytes
+	v6 := bip155Serialize(mustAddr("[2a01:4f8::1]:8333"))
+	if len(v6) != 20 || v6[0] != bip155IPv6 || v6[1] != 16 || v6[18] != 0x20 || v6[19] != 0x8d {
+		t.Errorf("ipv6 serialized as %x", v6)
+	}
+}
+
+/*
+
+ */
@@ -1,17 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"html"
 	"log"
 	"net/http"
+	"sort"
 	"text/template"
 	"time"
+
+	"github.com/miekg/dns"
 )
 
 // startHTTP runs in a goroutine and provides the web interface
 // to the dnsseeder
-func startHTTP(port string) {
+func startHTTP(addr string) {
 
 	http.HandleFunc("/dns", dnsWebHandler)
 	http.HandleFunc("/node", nodeHandler)
@@ -20,10 +24,13 @@ func startHTTP(port string) {
 	http.HandleFunc("/statusWG", statusWGHandler)
 	http.HandleFunc("/statusNG", statusNGHandler)
 	http.HandleFunc("/summary", summaryHandler)
-	http.HandleFunc("/seeds.txt", txtHandler)
+	http.HandleFunc("/groups", groupsHandler)
+	http.HandleFunc("/banned", bannedHandler)
+	http.HandleFunc("/seeds.txt", exportHandler)
+	http.HandleFunc("/export", exportHandler)
 	http.HandleFunc("/", emptyHandler)
-	// listen only on localhost
-	err := http.ListenAndServe("127.0.0.1:"+port, nil)
+	// listens only on localhost unless an address was given with the port
+	err := http.ListenAndServe(addr, nil)
 	if err != nil {
 		log.Fatal("ListenAndServe: ", err)
 	}
@@ -47,54 +54,6 @@ func dnsWebHandler(w http.ResponseWriter, r *http.Request) {
 
 	// FIXME - This is ugly code and needs to be cleaned up a lot
 
-	config.dnsmtx.RLock()
-	// if the dns map does not have a key for the request it will return an empty slice
-	v4std := config.dns[s.dnsHost+".A"]
-	v4non := config.dns["nonstd."+s.dnsHost+".A"]
-	v6std := config.dns[s.dnsHost+".AAAA"]
-	v6non := config.dns["nonstd."+s.dnsHost+".AAAA"]
-	config.dnsmtx.RUnlock()
-
-	var v4stdstr, v4nonstr []string
-	var v6stdstr, v6nonstr []string
-
-	if x := len(v4std); x > 0 {
-		v4stdstr = make([]string, x)
-		for k, v := range v4std {
-			v4stdstr[k] = v.String()
-		}
-	} else {
-		v4stdstr = []string{"No records Available"}
-	}
-
-	if x := len(v4non); x > 0 {
-		v4nonstr = make([]string, x)
-		for k, v := range v4non {
-			v4nonstr[k] = v.String()
-		}
-	} else {
-		v4nonstr = []string{"No records Available"}
-	}
-
-	// ipv6
-	if x := len(v6std); x > 0 {
-		v6stdstr = make([]string, x)
-		for k, v := range v6std {
-			v6stdstr[k] = v.String()
-		}
-	} else {
-		v6stdstr = []string{"No records Available"}
-	}
-
-	if x := len(v6non); x > 0 {
-		v6nonstr = make([]string, x)
-		for k, v := range v6non {
-			v6nonstr[k] = v.String()
-		}
-	} else {
-		v6nonstr = []string{"No records Available"}
-	}
-
 	t1 := `
 	<center>
 	<table border=1>
@@ -120,51 +79,84 @@ func dnsWebHandler(w http.ResponseWriter, r *http.Request) {
 	</center>
 	`
 
-	writeHeader(w, r)
-	fmt.Fprintf(w, "<b>Currently serving the following DNS records</b>")
-	fmt.Fprintf(w, "<p><center><b>IPv4</b></center></p>")
-	fmt.Fprintf(w, t1)
-
 	t := template.New("v4 template")
 	t, err := t.Parse(t2)
 	if err != nil {
 		log.Printf("error parsing template v4 %v\n", err)
 	}
-	err = t.Execute(w, v4stdstr)
-	if err != nil {
-		log.Printf("error executing template v4 %v\n", err)
-	}
 
-	fmt.Fprintf(w, t3)
+	// every name is served from the same nodes but each has its own records
+	s.mtx.RLock()
+	names := s.dnsNames
+	s.mtx.RUnlock()
 
-	err = t.Execute(w, v4nonstr)
-	if err != nil {
-		log.Printf("error executing template v4 non %v\n", err)
-	}
+	writeHeader(w, r)
+	for _, dn := range names {
+
+		config.dnsmtx.RLock()
+		// if the dns map does not have a key for the request it will return an empty slice
+		v4stdstr := rrStrings(config.dns[dn.host+".A"])
+		v4nonstr := rrStrings(config.dns["nonstd."+dn.host+".A"])
+		v6stdstr := rrStrings(config.dns[dn.host+".AAAA"])
+		v6nonstr := rrStrings(config.dns["nonstd."+dn.host+".AAAA"])
+		soa := config.dns[dn.host+".SOA"]
+		config.dnsmtx.RUnlock()
+
+		fmt.Fprintf(w, "<b>Currently serving the following DNS records for %s</b>", html.EscapeString(dn.host))
+		if len(soa) > 0 {
+			fmt.Fprintf(w, "<p><center>%s</center></p>", html.EscapeString(soa[0].String()))
+		}
+		fmt.Fprintf(w, "<p><center><b>IPv4</b></center></p>")
+		fmt.Fprintf(w, t1)
 
-	fmt.Fprintf(w, t4)
+		err = t.Execute(w, v4stdstr)
+		if err != nil {
+			log.Printf("error executing template v4 %v\n", err)
+		}
 
-	// ipv6 records
+		fmt.Fprintf(w, t3)
 
-	fmt.Fprintf(w, "<p><center><b>IPv6</b></center></p>")
-	fmt.Fprintf(w, t1)
+		err = t.Execute(w, v4nonstr)
+		if err != nil {
+			log.Printf("error executing template v4 non %v\n", err)
+		}
 
-	err = t.Execute(w, v6stdstr)
-	if err != nil {
-		log.Printf("error executing template v6 %v\n", err)
-	}
+		fmt.Fprintf(w, t4)
 
-	fmt.Fprintf(w, t3)
+		// ipv6 records
 
-	err = t.Execute(w, v6nonstr)
-	if err != nil {
-		log.Printf("error executing template v6 non %v\n", err)
-	}
+		fmt.Fprintf(w, "<p><center><b>IPv6</b></center></p>")
+		fmt.Fprintf(w, t1)
 
-	fmt.Fprintf(w, t4)
+		err = t.Execute(w, v6stdstr)
+		if err != nil {
+			log.Printf("error executing template v6 %v\n", err)
+		}
+
+		fmt.Fprintf(w, t3)
+
+		err = t.Execute(w, v6nonstr)
+		if err != nil {
+			log.Printf("error executing template v6 non %v\n", err)
+		}
+
+		fmt.Fprintf(w, t4)
+	}
 	writeFooter(w, r, st)
 }
 
+// rrStrings returns the text of each dns record for the dns page
+func rrStrings(rrs []dns.RR) []string {
+	if len(rrs) == 0 {
+		return []string{"No records Available"}
+	}
+	strs := make([]string, len(rrs))
+	for k, v := range rrs {
+		strs[k] = v.String()
+	}
+	return strs
+}
+
 // emptyHandler processes all requests for non-existant urls
 func emptyHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -301,7 +293,7 @@ func generateWebStatus(s *dnsseeder, status uint32) (ws []webstatus) {
 		}
 
 		ows := webstatus{
-			Key:    k,
+			Key:    k.String(),
 			Value:  valueStr,
 			Seeder: s.name,
 		}
@@ -313,25 +305,31 @@ func generateWebStatus(s *dnsseeder, status uint32) (ws []webstatus) {
 
 // copy Node details into a template friendly struct
 type webtemplate struct {
-	Key            string
-	IP             string
-	Port           uint16
-	Statusstr      string
-	Rating         string
-	Dnstype        string
-	Lastconnect    string
-	Lastconnectago string
-	Lasttry        string
-	Lasttryago     string
-	Crawlstart     string
-	Crawlstartago  string
-	Crawlactive    bool
-	Connectfails   uint32
-	Version        int32
-	Strversion     string
-	Services       string
-	Lastblock      int32
-	Nonstdip       string
+	Key             string
+	IP              string
+	Port            uint16
+	Statusstr       string
+	Rating          string
+	Dnstype         string
+	Lastconnect     string
+	Lastconnectago  string
+	Lasttry         string
+	Lasttryago      string
+	Crawlstart      string
+	Crawlstartago   string
+	Crawlactive     bool
+	Connectfails    uint32
+	Version         int32
+	Strversion      string
+	Services        string
+	Lastblock       int32
+	Nonstdip        string
+	Chainstatus     string
+	Chaincheckedago string
+	Probestatus     string
+	Probedago       string
+	Uptime          string
+	Table           string
 }
 
 // nodeHandler displays details about one node
@@ -359,6 +357,10 @@ func nodeHandler(w http.ResponseWriter, r *http.Request) {
       <tr><td>Remote SubVersion</td><td>{{.Strversion}}</td></tr>
       <tr><td>Remote Services</td><td>{{.Services}}</td></tr>
       <tr><td>Remote Last Block</td><td>{{.Lastblock}}</td></tr>
+      <tr><td>Chain Status</td><td>{{.Chainstatus}}<br>{{.Chaincheckedago}} ago</td></tr>
+      <tr><td>Block Probe</td><td>{{.Probestatus}}<br>{{.Probedago}} ago</td></tr>
+      <tr><td>Uptime 2h/8h/1d/7d/30d</td><td>{{.Uptime}}</td></tr>
+      <tr><td>Table</td><td>{{.Table}}</td></tr>
     </table>
     </center>
     `
@@ -375,31 +377,39 @@ func nodeHandler(w http.ResponseWriter, r *http.Request) {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
 
-	k := r.FormValue("nd")
+	k, err := parseNodeAddr(r.FormValue("nd"))
 	writeHeader(w, r)
-	if _, ok := s.theList[k]; ok == false {
+	if _, ok := s.theList[k]; err != nil || ok == false {
 		fmt.Fprintf(w, "Sorry there is no Node with those details\n")
 	} else {
 
 		nd := s.theList[k]
 		wt := webtemplate{
-			IP:             nd.na.IP.String(),
-			Port:           nd.na.Port,
-			Dnstype:        nd.dns2str(),
-			Nonstdip:       nd.nonstdIP.String(),
-			Statusstr:      nd.statusStr,
-			Lastconnect:    nd.lastConnect.String(),
-			Lastconnectago: time.Since(nd.lastConnect).String(),
-			Lasttry:        nd.lastTry.String(),
-			Lasttryago:     time.Since(nd.lastTry).String(),
-			Crawlstart:     nd.crawlStart.String(),
-			Crawlstartago:  time.Since(nd.crawlStart).String(),
-			Connectfails:   nd.connectFails,
-			Crawlactive:    nd.crawlActive,
-			Version:        nd.version,
-			Strversion:     nd.strVersion,
-			Services:       nd.services.String(),
-			Lastblock:      nd.lastBlock,
+			IP:              nd.addr.IP().String(),
+			Port:            nd.na.Port,
+			Dnstype:         nd.dns2str(),
+			Nonstdip:        nd.nonstdIP.String(),
+			Statusstr:       nd.statusStr,
+			Lastconnect:     nd.lastConnect.String(),
+			Lastconnectago:  time.Since(nd.lastConnect).String(),
+			Lasttry:         nd.lastTry.String(),
+			Lasttryago:      time.Since(nd.lastTry).String(),
+			Crawlstart:      nd.crawlStart.String(),
+			Crawlstartago:   time.Since(nd.crawlStart).String(),
+			Connectfails:    nd.connectFails,
+			Crawlactive:     nd.crawlActive,
+			Version:         nd.version,
+			Strversion:      nd.strVersion,
+			Services:        nd.services.String(),
+			Lastblock:       nd.lastBlock,
+			Chainstatus:     chain2str(nd.chainStatus),
+			Chaincheckedago: time.Since(nd.chainChecked).String(),
+			Probestatus:     probe2str(nd.probeStatus),
+			Probedago:       time.Since(nd.probed).String(),
+			Uptime: fmt.Sprintf("%.2f%% %.2f%% %.2f%% %.2f%% %.2f%%",
+				nd.uptime(stat2H), nd.uptime(stat8H), nd.uptime(stat1D), nd.uptime(stat7D), nd.uptime(stat30D)),
+			Table: fmt.Sprintf("%s bucket: %v slot: %v source: %s netgroup: %s",
+				table2str(nd.table), nd.bucket, nd.slot, nd.srcGroup, nd.group),
 		}
 
 		// display details for the Node
@@ -417,6 +427,232 @@ func nodeHandler(w http.ResponseWriter, r *http.Request) {
 	writeFooter(w, r, st)
 }
 
+// webgroup holds the node counts for one netgroup
+type webgroup struct {
+	Group string
+	Nodes int
+	CG    int
+	Share string
+}
+
+// groupsHandler displays how the nodes for a seeder are spread over netgroups
+func groupsHandler(w http.ResponseWriter, r *http.Request) {
+
+	st := time.Now()
+
+	// read the seeder name
+	n := r.FormValue("s")
+	s := getSeederByName(n)
+	if s == nil {
+		writeHeader(w, r)
+		fmt.Fprintf(w, "No seeder found called %s", html.EscapeString(n))
+		writeFooter(w, r, st)
+		return
+	}
+
+	// gather all the info before writing anything to the remote browser
+	s.mtx.RLock()
+	cg := make(map[string]int)
+	for _, nd := range s.theList {
+		if nd.status == statusCG {
+			cg[nd.group]++
+		}
+	}
+	total := len(s.theList)
+	wg := make([]webgroup, 0, len(s.groupCounts))
+	for g, c := range s.groupCounts {
+		wg = append(wg, webgroup{
+			Group: g,
+			Nodes: c,
+			CG:    cg[g],
+			Share: fmt.Sprintf("%.2f%%", 100.0*float64(c)/float64(total)),
+		})
+	}
+	maxPerGroup, maxDNSPerGrp := s.maxPerGroup, s.maxDNSPerGrp
+	s.mtx.RUnlock()
+
+	// largest groups first
+	sort.Slice(wg, func(i, j int) bool {
+		if wg[i].Nodes != wg[j].Nodes {
+			return wg[i].Nodes > wg[j].Nodes
+		}
+		return wg[i].Group < wg[j].Group
+	})
+
+	gt := `
+	<center>
+	<table border=1>
+	  <tr>
+	  <th>Netgroup</th>
+	  <th>Nodes</th>
+	  <th>statusCG</th>
+	  <th>Share</th>
+	  </tr>
+	     {{range .}}
+	  <tr>
+	  <td>{{.Group}}</td>
+	  <td>{{.Nodes}}</td>
+	  <td>{{.CG}}</td>
+	  <td>{{.Share}}</td>
+	  </tr>
+	     {{end}}
+	</table>
+	</center>
+	`
+
+	writeHeader(w, r)
+	fmt.Fprintf(w, "<center><b>Netgroups for %s - max %v nodes per group & %v per DNS answer</b></center>", html.EscapeString(s.name), maxPerGroup, maxDNSPerGrp)
+
+	if len(wg) == 0 {
+		fmt.Fprintf(w, "No Nodes found")
+	} else {
+		t := template.New("Groups template")
+		t, err := t.Parse(gt)
+		if err != nil {
+			log.Printf("error parsing groups template %v\n", err)
+		}
+		err = t.Execute(w, wg)
+		if err != nil {
+			log.Printf("error executing groups template %v\n", err)
+		}
+	}
+	writeFooter(w, r, st)
+}
+
+// webban holds the details of one ban list rule
+type webban struct {
+	Action  string
+	CIDR    string
+	Network string
+	Expires string
+	Reason  string
+}
+
+// bannedHandler displays the ban list rules for a seeder and the nodes they cover
+func bannedHandler(w http.ResponseWriter, r *http.Request) {
+
+	st := time.Now()
+
+	// read the seeder name
+	n := r.FormValue("s")
+	s := getSeederByName(n)
+	if s == nil {
+		writeHeader(w, r)
+		fmt.Fprintf(w, "No seeder found called %s", html.EscapeString(n))
+		writeFooter(w, r, st)
+		return
+	}
+
+	// gather all the info before writing anything to the remote browser
+	var wb []webban
+	for _, br := range config.bans.forNetwork(s.name) {
+		b := webban{
+			Action:  "ban",
+			CIDR:    br.ipnet.String(),
+			Network: br.network,
+			Expires: "never",
+			Reason:  br.reason,
+		}
+		if br.allow {
+			b.Action = "allow"
+		}
+		if br.network == "" {
+			b.Network = "all"
+		}
+		if br.expires.IsZero() == false {
+			b.Expires = br.expires.String()
+			if time.Now().After(br.expires) {
+				b.Expires += " (expired)"
+			}
+		}
+		wb = append(wb, b)
+	}
+
+	var ws []webstatus
+	s.mtx.RLock()
+	for k, nd := range s.theList {
+		if b, reason := s.isBanned(nd.na.IP); b {
+			ws = append(ws, webstatus{Key: k.String(), Value: reason, Seeder: s.name})
+		}
+	}
+	s.mtx.RUnlock()
+
+	s.counts.mtx.RLock()
+	rejected := s.counts.Banned
+	s.counts.mtx.RUnlock()
+
+	bt := `
+	<center>
+	<table border=1>
+	  <tr>
+	  <th>Action</th>
+	  <th>Range</th>
+	  <th>Network</th>
+	  <th>Expires</th>
+	  <th>Reason</th>
+	  </tr>
+	     {{range .}}
+	  <tr>
+	  <td>{{.Action}}</td>
+	  <td>{{.CIDR}}</td>
+	  <td>{{.Network}}</td>
+	  <td>{{.Expires}}</td>
+	  <td>{{.Reason}}</td>
+	  </tr>
+	     {{end}}
+	</table>
+	</center>
+	`
+	nt := `
+	<center>
+	<table border=1>
+	  <tr>
+	  <th>Node</th>
+	  <th>Reason</th>
+	  </tr>
+	     {{range .}}
+	  <tr>
+	  <td><a href="/node?s={{.Seeder}}&nd={{.Key}}">{{.Key}}</a></td>
+	  <td>{{.Value}}</td>
+	  </tr>
+	     {{end}}
+	</table>
+	</center>
+	`
+
+	writeHeader(w, r)
+	fmt.Fprintf(w, "<center><b>Ban list rules for %s</b></center>", html.EscapeString(s.name))
+	if len(wb) == 0 {
+		fmt.Fprintf(w, "No ban list rules")
+	} else {
+		t := template.New("Ban template")
+		t, err := t.Parse(bt)
+		if err != nil {
+			log.Printf("error parsing ban template %v\n", err)
+		}
+		err = t.Execute(w, wb)
+		if err != nil {
+			log.Printf("error executing ban template %v\n", err)
+		}
+	}
+
+	fmt.Fprintf(w, "<p><center><b>Banned nodes not being crawled or served. %v new addresses rejected</b></center></p>", rejected)
+	if len(ws) == 0 {
+		fmt.Fprintf(w, "No banned nodes")
+	} else {
+		t := template.New("Banned nodes template")
+		t, err := t.Parse(nt)
+		if err != nil {
+			log.Printf("error parsing banned nodes template %v\n", err)
+		}
+		err = t.Execute(w, ws)
+		if err != nil {
+			log.Printf("error executing banned nodes template %v\n", err)
+		}
+	}
+	writeFooter(w, r, st)
+}
+
 // summaryHandler displays details about one node
 func summaryHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -438,9 +674,26 @@ func summaryHandler(w http.ResponseWriter, r *http.Request) {
 		V6Std    uint32
 		V6Non    uint32
 		DNSTotal uint32
+		Names    string
+		Families string
+		Codec    string
+		Groups   int
+		New      int
+		Tried    int
+		Rejects  string
+		Banned   uint32
+		Evicted  uint32
+		Boots    uint32
+		LastBoot string
+		Policy   string
 	}
 
 	writeHeader(w, r)
+
+	// a network reload can change the seeders so hold them while we display them
+	config.smtx.RLock()
+	defer config.smtx.RUnlock()
+
 	// loop through each of the seeder name from a slice so they are always returned in
 	// the same order then get a pointer to the seeder struct
 	for _, n := range config.order {
@@ -464,8 +717,24 @@ func summaryHandler(w http.ResponseWriter, r *http.Request) {
 		hc.V6Std = s.counts.DNSCounts[dnsV6Std]
 		hc.V6Non = s.counts.DNSCounts[dnsV6Non]
 		hc.DNSTotal = hc.V4Std + hc.V4Non + hc.V6Std + hc.V6Non
+		hc.Names = s.names2str()
+		hc.Banned = s.counts.Banned
+		hc.Evicted = s.counts.Evicted
+		hc.Boots = s.counts.Bootstraps
 		s.counts.mtx.RUnlock()
 
+		hc.Families = s.families()
+		hc.Codec = s.codec.String()
+		hc.Policy = s.policy2str()
+		hc.Rejects = s.rejects2str()
+		hc.LastBoot = s.boots2str()
+
+		s.mtx.RLock()
+		hc.Groups = len(s.groupCounts)
+		hc.New = s.tables.nNew
+		hc.Tried = s.tables.nTried
+		s.mtx.RUnlock()
+
 		// we are using basic and simple html here. No fancy graphics or css
 		sp := `
     <b>Stats for seeder: {{.Name}}</b>
@@ -478,7 +747,14 @@ func summaryHandler(w http.ResponseWriter, r *http.Request) {
     <td><a href="/statusWG?s={{.Name}}">WG: {{.WG}}/{{.WGS}}</a></td>
     <td><a href="/statusNG?s={{.Name}}">NG: {{.NG}}/{{.NGS}}</a></td>
     <td>Total: {{.Total}}</td>
+    <td><a href="/groups?s={{.Name}}">Netgroups: {{.Groups}}</a></td>
+    <td><a href="/banned?s={{.Name}}">Banned: {{.Banned}}</a></td>
+    <td>Evicted: {{.Evicted}}</td>
+    <td>New/Tried: {{.New}}/{{.Tried}}</td>
     <td><a title="Export in format consumed by Bitcoin Core contrib/seeds" href="/seeds.txt?s={{.Name}}">seeds.txt</a></td>
+    <td><a href="/export?s={{.Name}}&amp;format=json">json</a></td>
+    <td><a href="/export?s={{.Name}}&amp;format=csv">csv</a></td>
+    <td><a title="Good nodes for Bitcoin Core chainparamsseeds.h" href="/export?s={{.Name}}&amp;format=chainparams&amp;status=CG&amp;pergroup=1">chainparams</a></td>
     </tr></table>
     </td><td>
     DNS Requests<br>
@@ -490,6 +766,12 @@ func summaryHandler(w http.ResponseWriter, r *http.Request) {
     <td><a href="/dns?s={{.Name}}">Total: {{.DNSTotal}}</a></td>
     </tr></table>
     </td></tr></table>
+    DNS names (requests): {{.Names}}<br>
+    Address families: {{.Families}}<br>
+    Message codec: {{.Codec}}<br>
+    Rejected addresses: {{.Rejects}}<br>
+    Bootstraps: {{.Boots}} Last: {{.LastBoot}}<br>
+    Crawl policy: {{.Policy}}
 	</center>
 	`
 		t := template.New("Header template")
@@ -506,50 +788,35 @@ func summaryHandler(w http.ResponseWriter, r *http.Request) {
 	writeFooter(w, r, st)
 }
 
-// txtHandler outputs the node list in the format expected by Bitcoin Core's
-// contrib/seeds script.
-func txtHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/plain")
+// exportHandler outputs the node list in the format and with the filters given in
+// the request. See exportOptions for the names
+func exportHandler(w http.ResponseWriter, r *http.Request) {
 
 	// read the seeder name
 	n := r.FormValue("s")
 	s := getSeederByName(n)
 	if s == nil {
+		w.Header().Set("Content-Type", "text/plain")
 		fmt.Fprintf(w, "No seeder found called %s\n", n)
 		return
 	}
 
-	fmt.Fprintf(w, "# address                                        good  lastSuccess    %%(2h)   %%(8h)   %%(1d)   %%(7d)  %%(30d)  blocks      svcs  version\n")
-
-	// gather all the info before writing anything to the remote browser
-	s.mtx.RLock()
-	defer s.mtx.RUnlock()
-
-	for k, v := range s.theList {
-		address := k
-
-		var good int
-		if v.status == statusCG {
-			good = 1
-		} else {
-			good = 0
-		}
-
-		lastSuccess := v.lastConnect
-
-		// Alas we don't actually measure this, so fake it.
-		uptime := (100.0 - float32(v.rating)) / 2.0 + 50.0
-
-		blocks := v.lastBlock
-
-		services := v.services
-
-		version := v.version
-
-		userAgent := v.strVersion
+	opts := make(map[string]string)
+	for _, o := range exportOptions {
+		opts[o] = r.FormValue(o)
+	}
+	if opts["format"] == "" {
+		opts["format"] = "txt"
+	}
 
-		fmt.Fprintf(w, "%s                                  %d   %d  %.2f%% %.2f%% %.2f%% %.2f%% %.2f%%  %d  %08x  %d %q\n", address, good, lastSuccess.Unix(), uptime, uptime, uptime, uptime, uptime, blocks, int32(services), version, userAgent)
+	// build the output first so an error can be returned instead of a partial list
+	var b bytes.Buffer
+	if err := s.export(&b, opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	w.Header().Set("Content-Type", exporters[opts["format"]].contentType())
+	w.Write(b.Bytes())
 }
 
 // writeHeader will output the standard header
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Bitcoin Core saves its address manager to peers.dat and the nodes it was connected to
+// at shutdown to anchors.dat. Both files start with the network magic and end with a
+// double sha256 checksum of everything before it
+const (
+	srcImport = "import:" // prefix for the source group of imported addresses
+
+	peersV3BIP155     = 3       // first peers.dat format to store addresses in addrv2 form
+	peersMaxFormat    = 4       // newest peers.dat format we understand
+	peersCompatBase   = 32      // added to the lowest compatible format in the peers.dat header
+	diskVersionAddrV2 = 1 << 29 // CAddress disk version flag for addrv2 encoding
+	maxImportAddrs    = 1000000 // sanity limit on the number of addresses in a file
+	bip155IPv4        = 1       // BIP155 network id for ipv4
+	bip155IPv6        = 2       // BIP155 network id for ipv6
+	maxBIP155AddrSize = 512     // max address size allowed by BIP155
+	checksumSize      = chainhash.HashSize
+)
+
+var errShortFile = errors.New("file is truncated")
+
+// importFile holds a file to import and the network it is for. An empty network
+// means it is found from the magic in the file
+type importFile struct {
+	network string
+	fName   string
+}
+
+// parseImports splits a comma separated list of [network=]file entries
+func parseImports(list string) []importFile {
+	var ifs []importFile
+	for _, e := range strings.Split(list, ",") {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+		var f importFile
+		if i := strings.Index(e, "="); i > 0 {
+			f.network, f.fName = e[:i], e[i+1:]
+		} else {
+			f.fName = e
+		}
+		ifs = append(ifs, f)
+	}
+	return ifs
+}
+
+// readImportFile reads the addresses from a peers.dat, anchors.dat or text seed file.
+// It returns the magic from a binary file or 0 for a text file
+func readImportFile(fName string) (wire.BitcoinNet, []bootAddr, error) {
+
+	data, err := ioutil.ReadFile(fName)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Error reading import file %s: %v", fName, err)
+	}
+	base := filepath.Base(fName)
+
+	if strings.HasSuffix(base, ".dat") == false {
+		addrs, err := readSeedList(bytes.NewReader(data), srcImport+base)
+		if err != nil {
+			return 0, nil, fmt.Errorf("Error reading seed file %s: %v", fName, err)
+		}
+		return 0, addrs, nil
+	}
+
+	if len(data) < 4+checksumSize {
+		return 0, nil, fmt.Errorf("Error reading %s: %v", fName, errShortFile)
+	}
+	body := data[:len(data)-checksumSize]
+	if bytes.Equal(chainhash.DoubleHashB(body), data[len(body):]) == false {
+		return 0, nil, fmt.Errorf("Error reading %s: checksum mismatch", fName)
+	}
+	magic := wire.BitcoinNet(binary.LittleEndian.Uint32(body))
+
+	var addrs []bootAddr
+	if base == "anchors.dat" {
+		addrs, err = readAnchors(bytes.NewReader(body[4:]), srcImport+base)
+	} else {
+		addrs, err = readPeersDat(bytes.NewReader(body[4:]), srcImport+base)
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("Error reading %s: %v", fName, err)
+	}
+	return magic, addrs, nil
+}
+
+// readPeersDat reads the new and tried addresses from a Bitcoin Core peers.dat file after
+// the magic. Each address keeps the netgroup of the node that told Bitcoin Core about it
+// so the new table limits still apply to the imported addresses
+func readPeersDat(r io.Reader, src string) ([]bootAddr, error) {
+
+	var hdr struct {
+		Format  uint8
+		Compat  uint8
+		Key     [32]byte
+		New     int32
+		Tried   int32
+		Buckets int32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return nil, errShortFile
+	}
+	if int(hdr.Compat)-peersCompatBase > peersMaxFormat {
+		return nil, fmt.Errorf("peers.dat format %v needs a newer version to read", hdr.Format)
+	}
+	if hdr.New < 0 || hdr.Tried < 0 || int(hdr.New)+int(hdr.Tried) > maxImportAddrs {
+		return nil, fmt.Errorf("invalid address counts new: %v tried: %v", hdr.New, hdr.Tried)
+	}
+	v2 := hdr.Format >= peersV3BIP155
+
+	var addrs []bootAddr
+	for i := 0; i < int(hdr.New)+int(hdr.Tried); i++ {
+		na, err := readDiskAddress(r)
+		if err != nil {
+			return nil, err
+		}
+		source, err := readNetAddr(r, v2)
+		if err != nil {
+			return nil, err
+		}
+		// last success and attempts
+		var info [12]byte
+		if _, err = io.ReadFull(r, info[:]); err != nil {
+			return nil, errShortFile
+		}
+		if na == nil {
+			continue
+		}
+		asrc := src
+		if source != nil {
+			asrc = src + ":" + netGroup(source)
+		}
+		addrs = append(addrs, bootAddr{na: na, src: asrc})
+	}
+	return addrs, nil
+}
+
+// readAnchors reads the addresses from a Bitcoin Core anchors.dat file after the magic
+func readAnchors(r io.Reader, src string) ([]bootAddr, error) {
+
+	n, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, errShortFile
+	}
+	if n > maxImportAddrs {
+		return nil, fmt.Errorf("invalid address count %v", n)
+	}
+
+	var addrs []bootAddr
+	for i := uint64(0); i < n; i++ {
+		na, err := readDiskAddress(r)
+		if err != nil {
+			return nil, err
+		}
+		if na != nil {
+			addrs = append(addrs, bootAddr{na: na, src: src})
+		}
+	}
+	return addrs, nil
+}
+
+// readDiskAddress reads a CAddress in the Bitcoin Core disk format. It returns a nil
+// address for networks we can not crawl such as tor and i2p
+func readDiskAddress(r io.Reader) (*wire.NetAddress, error) {
+
+	var hdr struct {
+		Version uint32
+		Time    uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return nil, errShortFile
+	}
+	v2 := hdr.Version&diskVersionAddrV2 != 0
+
+	var services uint64
+	var err error
+	if v2 {
+		services, err = wire.ReadVarInt(r, 0)
+	} else {
+		err = binary.Read(r, binary.LittleEndian, &services)
+	}
+	if err != nil {
+		return nil, errShortFile
+	}
+
+	ip, err := readNetAddr(r, v2)
+	if err != nil {
+		return nil, err
+	}
+	var port uint16
+	if err = binary.Read(r, binary.BigEndian, &port); err != nil {
+		return nil, errShortFile
+	}
+	if ip == nil {
+		return nil, nil
+	}
+	return wire.NewNetAddressTimestamp(time.Unix(int64(hdr.Time), 0), wire.ServiceFlag(services), ip, port), nil
+}
+
+// readNetAddr reads a CNetAddr in the legacy 16 byte form or the BIP155 addrv2 form.
+// It returns a nil ip for networks other than ipv4 and ipv6
+func readNetAddr(r io.Reader, v2 bool) (net.IP, error) {
+
+	if v2 == false {
+		ip := make(net.IP, net.IPv6len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return nil, errShortFile
+		}
+		return ip, nil
+	}
+
+	var id [1]byte
+	if _, err := io.ReadFull(r, id[:]); err != nil {
+		return nil, errShortFile
+	}
+	size, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, errShortFile
+	}
+	if size > maxBIP155AddrSize {
+		return nil, fmt.Errorf("address size %v is too large", size)
+	}
+	b := make([]byte, size)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return nil, errShortFile
+	}
+
+	switch {
+	case id[0] == bip155IPv4 && size == net.IPv4len:
+		return net.IP(b), nil
+	case id[0] == bip155IPv6 && size == net.IPv6len:
+		return net.IP(b), nil
+	}
+	return nil, nil
+}
+
+// readSeedList reads a nodes_main.txt style list or our own seeds.txt output. The address
+// is the first field of each line and lines starting with # are comments. Addresses we
+// can not crawl such as onion addresses are skipped
+func readSeedList(r io.Reader, src string) ([]bootAddr, error) {
+
+	var addrs []bootAddr
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		a, err := parseNodeAddr(fields[0])
+		if err != nil {
+			continue
+		}
+		// seed lists have no timestamp so they are treated as just seen
+		addrs = append(addrs, bootAddr{na: wire.NewNetAddressIPPort(a.IP(), a.Port(), 0), src: src})
+	}
+	return addrs, sc.Err()
+}
+
+// importAddrs feeds imported addresses through addNa and returns the number added
+func (s *dnsseeder) importAddrs(addrs []bootAddr) int {
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	c := 0
+	for _, ba := range addrs {
+		if s.addNa(ba.na, ba.src) == true {
+			c++
+		}
+	}
+	return c
+}
+
+// importFiles reads each import file and adds the addresses to the seeder it is for.
+// Binary files are matched to a seeder by their magic and text files need the network
+// name unless only one network is loaded
+func importFiles(ifs []importFile, seeders map[string]*dnsseeder) error {
+
+	for _, f := range ifs {
+		magic, addrs, err := readImportFile(f.fName)
+		if err != nil {
+			return err
+		}
+
+		var s *dnsseeder
+		switch {
+		case f.network != "":
+			if s = seeders[f.network]; s == nil {
+				return fmt.Errorf("Error importing %s: no network called %s", f.fName, f.network)
+			}
+			if magic != 0 && magic != s.id {
+				return fmt.Errorf("Error importing %s: file is for network magic %s not %s", f.fName, magic, s.name)
+			}
+		case magic != 0:
+			for _, ns := range seeders {
+				if ns.id == magic {
+					s = ns
+				}
+			}
+			if s == nil {
+				return fmt.Errorf("Error importing %s: no network loaded with magic %s", f.fName, magic)
+			}
+		case len(seeders) == 1:
+			for _, ns := range seeders {
+				s = ns
+			}
+		default:
+			var names []string
+			for n := range seeders {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("Error importing: use <network>=%s to say which network it is for. Loaded networks: %s",
+				f.fName, strings.Join(names, ", "))
+		}
+
+		c := s.importAddrs(addrs)
+		log.Printf("%s: imported %v of %v addresses from %s\n", s.name, c, len(addrs), f.fName)
+	}
+	return nil
+}
+
+// runImport is the import subcommand. It adds the addresses from the files to the node
+// snapshots in the data directory so they are crawled when the seeder next starts
+func runImport(args []string) error {
+
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	nf := fs.String("netfile", "", "List of json config files to load")
+	fs.StringVar(&config.datadir, "datadir", "", "Directory with the node snapshots to import into")
+	fs.BoolVar(&config.verbose, "v", false, "Display verbose output")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s import -netfile <file[,file2]> -datadir <dir> [network=]file ...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *nf == "" || config.datadir == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("Error - import needs -netfile, -datadir and at least one file to import")
+	}
+	if err := os.MkdirAll(config.datadir, 0755); err != nil {
+		return fmt.Errorf("Error creating data directory %s - %v", config.datadir, err)
+	}
+
+	seeders, _, err := loadNetworks(strings.Split(*nf, ","))
+	if err != nil {
+		return err
+	}
+
+	// add to the nodes we already know about rather than replace them
+	for _, s := range seeders {
+		if _, err = s.loadNodes(); err != nil {
+			return err
+		}
+	}
+	if err = importFiles(parseImports(strings.Join(fs.Args(), ",")), seeders); err != nil {
+		return err
+	}
+	for _, s := range seeders {
+		if err = s.saveNodes(); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %v nodes saved to %s\n", s.name, len(s.theList), s.snapFile())
+	}
+	return nil
+}
+
+/*
+
+ */
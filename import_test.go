@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// testAddr is an address written into the test peers.dat and anchors.dat files
+type testAddr struct {
+	id     byte   // BIP155 network id
+	addr   []byte // raw address
+	port   uint16
+	source net.IP
+}
+
+// writeNetAddr writes a CNetAddr in the legacy or addrv2 form
+func writeNetAddr(b *bytes.Buffer, v2 bool, id byte, addr []byte) {
+	if v2 {
+		b.WriteByte(id)
+		wire.WriteVarInt(b, 0, uint64(len(addr)))
+		b.Write(addr)
+		return
+	}
+	b.Write(net.IP(addr).To16())
+}
+
+// writeDiskAddress writes a CAddress in the Bitcoin Core disk format
+func writeDiskAddress(b *bytes.Buffer, v2 bool, ts time.Time, ta testAddr) {
+	version := uint32(220000)
+	if v2 {
+		version |= diskVersionAddrV2
+	}
+	binary.Write(b, binary.LittleEndian, version)
+	binary.Write(b, binary.LittleEndian, uint32(ts.Unix()))
+	if v2 {
+		wire.WriteVarInt(b, 0, uint64(wire.SFNodeNetwork))
+	} else {
+		binary.Write(b, binary.LittleEndian, uint64(wire.SFNodeNetwork))
+	}
+	writeNetAddr(b, v2, ta.id, ta.addr)
+	binary.Write(b, binary.BigEndian, ta.port)
+}
+
+// writeDatFile adds the magic and checksum to the body and writes it to a file
+func writeDatFile(t *testing.T, fName string, magic wire.BitcoinNet, body []byte) {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, uint32(magic))
+	b.Write(body)
+	b.Write(chainhash.DoubleHashB(b.Bytes()))
+	if err := ioutil.WriteFile(fName, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// peersDat returns the body of a peers.dat file in the given format
+func peersDat(format uint8, ts time.Time, addrs []testAddr) []byte {
+	var b bytes.Buffer
+	v2 := format >= peersV3BIP155
+	b.WriteByte(format)
+	b.WriteByte(peersCompatBase + format)
+	b.Write(make([]byte, 32))
+	binary.Write(&b, binary.LittleEndian, int32(len(addrs)))
+	binary.Write(&b, binary.LittleEndian, int32(0))
+	binary.Write(&b, binary.LittleEndian, int32(1024^(1<<30)))
+	for _, ta := range addrs {
+		writeDiskAddress(&b, v2, ts, ta)
+		writeNetAddr(&b, v2, bip155IPv4, ta.source.To4())
+		binary.Write(&b, binary.LittleEndian, ts.Unix())
+		binary.Write(&b, binary.LittleEndian, int32(0))
+	}
+	// bucket details follow the addresses but are not read
+	b.Write(make([]byte, 64))
+	return b.Bytes()
+}
+
+func TestImportFiles(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	addrs := []testAddr{
+		{bip155IPv4, net.ParseIP("1.2.3.4").To4(), 8333, net.ParseIP("9.9.1.1")},
+		{bip155IPv6, net.ParseIP("2a01:4f8::1"), 8333, net.ParseIP("9.8.1.1")},
+		{4, make([]byte, 32), 8333, net.ParseIP("9.9.1.1")}, // torv3 is skipped
+	}
+
+	peersV3 := filepath.Join(dir, "peers.dat")
+	writeDatFile(t, peersV3, wire.MainNet, peersDat(3, now, addrs))
+
+	magic, got, err := readImportFile(peersV3)
+	if err != nil {
+		t.Fatalf("unable to read peers.dat: %v", err)
+	}
+	if magic != wire.MainNet || len(got) != 2 {
+		t.Fatalf("magic: %s addresses: %v expected: %s 2", magic, len(got), wire.MainNet)
+	}
+	if got[1].na.IP.String() != "2a01:4f8::1" || got[1].na.Port != 8333 || got[1].na.Timestamp.Unix() != now.Unix() {
+		t.Errorf("address: %s:%v time: %v", got[1].na.IP, got[1].na.Port, got[1].na.Timestamp)
+	}
+	if got[0].src != "import:peers.dat:9.9.0.0/16" {
+		t.Errorf("source: %s expected: import:peers.dat:9.9.0.0/16", got[0].src)
+	}
+
+	// the older format stores every address in 16 bytes
+	legacy := filepath.Join(dir, "old", "peers.dat")
+	os.Mkdir(filepath.Dir(legacy), 0755)
+	writeDatFile(t, legacy, wire.MainNet, peersDat(2, now, addrs[:2]))
+	if _, got, err = readImportFile(legacy); err != nil || len(got) != 2 || got[0].na.IP.String() != "1.2.3.4" {
+		t.Errorf("legacy peers.dat: %v addresses: %v", err, len(got))
+	}
+
+	// anchors.dat holds a list of addrv2 addresses
+	var ab bytes.Buffer
+	wire.WriteVarInt(&ab, 0, 2)
+	writeDiskAddress(&ab, true, now, addrs[0])
+	writeDiskAddress(&ab, true, now, addrs[2])
+	anchors := filepath.Join(dir, "anchors.dat")
+	writeDatFile(t, anchors, wire.TestNet3, ab.Bytes())
+	if magic, got, err = readImportFile(anchors); err != nil || magic != wire.TestNet3 || len(got) != 1 {
+		t.Errorf("anchors.dat: %v magic: %s addresses: %v", err, magic, len(got))
+	}
+
+	// a damaged file is rejected
+	data, _ := ioutil.ReadFile(peersV3)
+	data[20] ^= 0xff
+	bad := filepath.Join(dir, "bad.dat")
+	ioutil.WriteFile(bad, data, 0644)
+	if _, _, err = readImportFile(bad); err == nil {
+		t.Errorf("damaged peers.dat was read")
+	}
+
+	// text seed lists in the contrib/seeds and seeds.txt formats
+	seeds := filepath.Join(dir, "nodes_main.txt")
+	ioutil.WriteFile(seeds, []byte(`# comment
+5.6.7.8:8333
+[2a01:4f8::2]:8333 # node
+abcdefghijklmnop.onion:8333
+6.7.8.9:8333                                  1   1600000000  100.00%
+`), 0644)
+	if magic, got, err = readImportFile(seeds); err != nil || magic != 0 || len(got) != 3 {
+		t.Errorf("seed list: %v magic: %s addresses: %v", err, magic, len(got))
+	}
+
+	// files are matched to the network by magic or name
+	seeders := map[string]*dnsseeder{}
+	for _, n := range []struct {
+		name string
+		id   wire.BitcoinNet
+	}{{"MainNet", wire.MainNet}, {"TestNet", wire.TestNet3}} {
+		s := &dnsseeder{name: n.name, id: n.id, port: 8333, maxSize: 1250}
+		s.theList = make(map[nodeAddr]*node)
+		seeders[n.name] = s
+	}
+	if err = importFiles(parseImports(peersV3+","+anchors+",MainNet="+seeds), seeders); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if len(seeders["MainNet"].theList) != 5 || len(seeders["TestNet"].theList) != 1 {
+		t.Errorf("MainNet nodes: %v TestNet nodes: %v expected: 5 1", len(seeders["MainNet"].theList), len(seeders["TestNet"].theList))
+	}
+	if err = importFiles(parseImports(seeds), seeders); err == nil {
+		t.Errorf("seed list imported without a network name")
+	} else if want := "use <network>=" + seeds + " to say which network it is for. Loaded networks: MainNet, TestNet"; strings.Contains(err.Error(), want) == false {
+		t.Errorf("error: %v expected it to contain: %s", err, want)
+	}
+	if err = importFiles(parseImports("TestNet="+peersV3), seeders); err == nil {
+		t.Errorf("peers.dat imported into the wrong network")
+	}
+
+	// a peers.dat that has been on disk for days is still imported in full
+	stale := filepath.Join(dir, "stale", "peers.dat")
+	os.Mkdir(filepath.Dir(stale), 0755)
+	writeDatFile(t, stale, wire.MainNet, peersDat(3, now.Add(-72*time.Hour), addrs))
+	s := &dnsseeder{name: "MainNet", id: wire.MainNet, port: 8333, maxSize: 1250}
+	s.theList = make(map[nodeAddr]*node)
+	if err = importFiles(parseImports(stale), map[string]*dnsseeder{s.name: s}); err != nil || len(s.theList) != 2 {
+		t.Errorf("stale peers.dat: %v nodes: %v expected: 2", err, len(s.theList))
+	}
+}
+
+/*
+
+ */
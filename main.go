@@ -1,5 +1,4 @@
 /*
-
  */
 package main
 
@@ -7,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
 	"os/signal"
 	"strings"
@@ -20,31 +20,46 @@ import (
 
 // NodeCounts holds various statistics about the running system for use in html templates
 type NodeCounts struct {
-	NdStatus  []uint32     // number of nodes at each of the 4 statuses - RG, CG, WG, NG
-	NdStarts  []uint32     // number of crawles started last startcrawlers run
-	DNSCounts []uint32     // number of dns requests for each dns type - dnsV4Std, dnsV4Non, dnsV6Std, dnsV6Non
-	mtx       sync.RWMutex // protect the structures
+	NdStatus   []uint32                // number of nodes at each of the 4 statuses - RG, CG, WG, NG
+	NdStarts   []uint32                // number of crawles started last startcrawlers run
+	DNSCounts  []uint32                // number of dns requests for each dns type - dnsV4Std, dnsV4Non, dnsV6Std, dnsV6Non
+	Rejects    [maxRouteReasons]uint32 // number of addresses rejected for each routability reason
+	Banned     uint32                  // number of addresses rejected by the ban list
+	Evicted    uint32                  // number of nodes evicted to make room for new addresses
+	Bootstraps uint32                  // number of times we have bootstrapped from the other seeders
+	NameCounts map[string]uint32       // number of dns requests for each dns name
+	mtx        sync.RWMutex            // protect the structures
 }
 
 // configData holds information on the application
 type configData struct {
-	dnsUnknown uint64                // the number of dns requests for we are not configured to handle
-	uptime     time.Time             // application start time
-	port       string                // port for the dns server to listen on
-	http       string                // port for the web server to listen on
-	version    string                // application version
-	seeders    map[string]*dnsseeder // holds a pointer to all the current seeders
-	smtx       sync.RWMutex          // protect the seeders map
-	order      []string              // the order of loading the netfiles so we can display in this order
-	dns        map[string][]dns.RR   // holds details of all the currently served dns records
-	dnsmtx     sync.RWMutex          // protect the dns map
-	verbose    bool                  // verbose output cmdline option
-	debug      bool                  // debug cmdline option
-	stats      bool                  // stats cmdline option
+	dnsUnknown   uint64                // the number of dns requests for we are not configured to handle
+	activeCrawls int32                 // number of crawls currently running across all seeders
+	maxCrawls    int                   // max number of crawls that can be running across all seeders
+	uptime       time.Time             // application start time
+	port         string                // port for the dns server to listen on
+	http         string                // port for the web server to listen on
+	datadir      string                // directory for node snapshots. Empty for no snapshots
+	asmap        *asMap                // maps addresses to ASN for netgroups or nil for /16 & /32 groups
+	bans         *banList              // operator ban and allow rules or nil for no ban list
+	version      string                // application version
+	seeders      map[string]*dnsseeder // holds a pointer to all the current seeders
+	smtx         sync.RWMutex          // protect the seeders map
+	order        []string              // the order of loading the netfiles so we can display in this order
+	dns          map[string][]dns.RR   // holds details of all the currently served dns records
+	dnsmtx       sync.RWMutex          // protect the dns map
+	verbose      bool                  // verbose output cmdline option
+	debug        bool                  // debug cmdline option
+	stats        bool                  // stats cmdline option
 }
 
 var config configData
 var netfile string
+var asmapFile string
+var banFile string
+var importList string
+var configFile string
+var logFile string
 
 func main() {
 
@@ -52,42 +67,135 @@ func main() {
 
 	config.version = "0.9.1"
 	config.uptime = time.Now()
+	rand.Seed(config.uptime.UnixNano())
 
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		// the report is on stdout so the error goes to stderr
+		if err := runCheck(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate-config" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExport(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	flag.StringVar(&configFile, "config", "", "Server config file. Flags and "+envConfig+" style environment variables take precedence over it")
 	flag.StringVar(&netfile, "netfile", "", "List of json config files to load")
-	flag.StringVar(&config.port, "p", "8053", "DNS Port to listen on")
-	flag.StringVar(&config.http, "w", "", "Web Port to listen on. No port specified & no web server running")
-	flag.BoolVar(&j, "j", false, "Write network template file (dnsseeder.json) and exit")
+	flag.StringVar(&config.port, "p", "8053", "DNS [address:]port to listen on")
+	flag.StringVar(&config.http, "w", "", "Web [address:]port to listen on. A port on its own listens on localhost. No port specified & no web server running")
+	flag.StringVar(&config.datadir, "datadir", "", "Directory to save node snapshots in for a warm start. No directory & no snapshots")
+	flag.StringVar(&asmapFile, "asmap", "", "File of 'prefix ASN' lines used to group nodes by ASN. No file & nodes are grouped by /16 or /32")
+	flag.StringVar(&banFile, "banlist", "", "JSON file of ban and allow rules. Reloaded on SIGHUP")
+	flag.StringVar(&importList, "import", "", "List of [network=]file peers.dat, anchors.dat or seed files to import at startup")
+	flag.IntVar(&config.maxCrawls, "maxcrawls", 1000, "Max number of crawls running at once across all networks. 0 for no limit")
+	flag.BoolVar(&j, "j", false, "Write network template file (dnsseeder.json) and exit. Add a preset name to write the file for a known network")
 	flag.BoolVar(&config.verbose, "v", false, "Display verbose output")
 	flag.BoolVar(&config.debug, "d", false, "Display debug output")
 	flag.BoolVar(&config.stats, "s", false, "Display stats output")
+	flag.StringVar(&logFile, "logfile", "", "File to append the log to. No file & the log goes to stderr")
 	flag.Parse()
 
 	if j == true {
-		createNetFile()
-		fmt.Printf("Template file has been created\n")
+		if flag.NArg() == 0 {
+			createNetFile()
+			fmt.Printf("Template file has been created\n")
+			os.Exit(0)
+		}
+		fName, err := writePreset(flag.Arg(0))
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Network file %s has been created. Set DNSName before loading it\n", fName)
 		os.Exit(0)
 	}
 
-	// configure the network options so we can start crawling
-	netwFiles := strings.Split(netfile, ",")
-	if len(netwFiles) == 0 {
-		fmt.Printf("Error - No filenames specified. Please add -net=<file[, file2]> to load these files\n")
+	// settings from the environment and the server config file for the flags not given
+	cfgFile, err := applySettings(flag.CommandLine, os.Getenv)
+	if err != nil {
+		fmt.Printf("%v\n", err)
 		os.Exit(1)
 	}
 
-	config.seeders = make(map[string]*dnsseeder)
-	config.dns = make(map[string][]dns.RR)
-	config.order = []string{}
+	if logFile != "" {
+		lf, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Printf("Error opening log file %s - %v\n", logFile, err)
+			os.Exit(1)
+		}
+		defer lf.Close()
+		log.SetOutput(lf)
+	}
+
+	// configure the network options so we can start crawling. Network files on the
+	// command line or in the environment are used over the server config file networks
+	netwFiles := strings.Split(netfile, ",")
+	loadNets := networkLoader(func() (map[string]*dnsseeder, []string, error) { return loadNetworks(netwFiles) })
+	if netfile == "" {
+		if cfgFile == "" {
+			fmt.Printf("Error - No filenames specified. Please add -netfile=<file[,file2]> or -config=<file> to load the networks\n")
+			os.Exit(1)
+		}
+		loadNets = func() (map[string]*dnsseeder, []string, error) { return serverNetworks(cfgFile) }
+	}
+
+	if config.datadir != "" {
+		if err := os.MkdirAll(config.datadir, 0755); err != nil {
+			fmt.Printf("Error creating data directory %s - %v\n", config.datadir, err)
+			os.Exit(1)
+		}
+	}
+
+	if asmapFile != "" {
+		am, err := loadASMap(asmapFile)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		config.asmap = am
+		fmt.Printf("Loaded %v prefixes from asmap file %s\n", am.entries, asmapFile)
+	}
 
-	for _, nwFile := range netwFiles {
-		nnw, err := loadNetwork(nwFile)
+	if banFile != "" {
+		bl, err := newBanList(banFile)
 		if err != nil {
-			fmt.Printf("Error loading data from netfile %s - %v\n", nwFile, err)
+			fmt.Printf("%v\n", err)
 			os.Exit(1)
 		}
-		if nnw != nil {
-			config.seeders[nnw.name] = nnw
-			config.order = append(config.order, nnw.name)
+		config.bans = bl
+	}
+
+	config.dns = make(map[string][]dns.RR)
+
+	if config.seeders, config.order, err = loadNets(); err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	if importList != "" {
+		if err = importFiles(parseImports(importList), config.seeders); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
 		}
 	}
 
@@ -109,14 +217,14 @@ func main() {
 
 	// start the web interface if we want it running
 	if config.http != "" {
-		go startHTTP(config.http)
+		go startHTTP(webListenAddr(config.http))
 	}
 
 	// start dns server
 	dns.HandleFunc(".", handleDNS)
-	go serve("udp", config.port)
+	go serve("udp", dnsListenAddr(config.port))
 	// RFC 7766 Sec. 5: "Authoritative server implementations MUST support TCP"
-	go serve("tcp", config.port)
+	go serve("tcp", dnsListenAddr(config.port))
 
 	var wg sync.WaitGroup
 
@@ -128,10 +236,22 @@ func main() {
 	}
 
 	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	// block until a signal is received
-	fmt.Println("\nShutting down on signal:", <-sig)
+	// block until a signal is received. SIGHUP reloads the ban list and network files
+	for sg := range sig {
+		if sg != syscall.SIGHUP {
+			fmt.Println("\nShutting down on signal:", sg)
+			break
+		}
+		if config.bans != nil {
+			if err := config.bans.reload(); err != nil {
+				log.Printf("status - unable to reload ban list - %v\n", err)
+			}
+		}
+		log.Printf("status - reloading network files\n")
+		reloadFrom(loadNets, done, &wg)
+	}
 
 	// FIXME - call dns server.Shutdown()
 
@@ -184,6 +304,8 @@ func updateDNSCounts(name, qtype string) {
 	var ndType uint32
 	var counted bool
 
+	// dns names are not case sensitive and we store ours in lower case
+	name = strings.ToLower(name)
 	nonstd := strings.HasPrefix(name, "nonstd.")
 
 	switch qtype {
@@ -204,11 +326,17 @@ func updateDNSCounts(name, qtype string) {
 	}
 
 	// for DNS requests we do not have a reference to a seeder so we have to find it
+	config.smtx.RLock()
+	defer config.smtx.RUnlock()
 	for _, s := range config.seeders {
 		s.counts.mtx.Lock()
 
-		if name == s.dnsHost+"." || name == "nonstd."+s.dnsHost+"." {
+		if host, ok := s.servesName(name); ok {
 			s.counts.DNSCounts[ndType]++
+			if s.counts.NameCounts == nil {
+				s.counts.NameCounts = make(map[string]uint32)
+			}
+			s.counts.NameCounts[host]++
 			counted = true
 		}
 		s.counts.mtx.Unlock()
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// legacySeeder matches the numbered Seeder1, Seeder2 ... fields from older network files
+var legacySeeder = regexp.MustCompile(`(?i)^seeder([0-9]+)$`)
+
+// migrateNetwork converts the fields of an older network file to the current names.
+// InitialIP was a comma separated string and each seeder had its own numbered field.
+// It returns the fields of the file and a note for each change made
+func migrateNetwork(data []byte) (map[string]json.RawMessage, []string, error) {
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, nil, err
+	}
+
+	var notes []string
+	var initialIPs, seeders []string
+	type numSeeder struct {
+		n    int
+		host string
+	}
+	var numbered []numSeeder
+
+	for k, v := range fields {
+		switch {
+		case strings.EqualFold(k, "InitialIP"):
+			var ips string
+			if err := json.Unmarshal(v, &ips); err != nil {
+				return nil, nil, fmt.Errorf("Invalid %s - %v", k, err)
+			}
+			for _, ip := range strings.Split(ips, ",") {
+				if ip = strings.TrimSpace(ip); ip != "" {
+					initialIPs = append(initialIPs, ip)
+				}
+			}
+			delete(fields, k)
+			notes = append(notes, k+" to InitialIPs")
+		case legacySeeder.MatchString(k):
+			var host string
+			if err := json.Unmarshal(v, &host); err != nil {
+				return nil, nil, fmt.Errorf("Invalid %s - %v", k, err)
+			}
+			n, _ := strconv.Atoi(legacySeeder.FindStringSubmatch(k)[1])
+			if host = strings.TrimSpace(host); host != "" {
+				numbered = append(numbered, numSeeder{n, host})
+			}
+			delete(fields, k)
+			notes = append(notes, k+" to Seeders")
+		}
+	}
+
+	sort.Slice(numbered, func(i, j int) bool { return numbered[i].n < numbered[j].n })
+	for _, ns := range numbered {
+		seeders = append(seeders, ns.host)
+	}
+
+	// the old values are added after any that are already in the current fields
+	merge := func(name string, add []string) error {
+		if len(add) == 0 {
+			return nil
+		}
+		var cur []string
+		for k, v := range fields {
+			if strings.EqualFold(k, name) {
+				if err := json.Unmarshal(v, &cur); err != nil {
+					return fmt.Errorf("Invalid %s - %v", k, err)
+				}
+				delete(fields, k)
+			}
+		}
+		j, err := json.Marshal(append(cur, add...))
+		if err != nil {
+			return err
+		}
+		fields[name] = j
+		return nil
+	}
+	if err := merge("InitialIPs", initialIPs); err != nil {
+		return nil, nil, err
+	}
+	if err := merge("Seeders", seeders); err != nil {
+		return nil, nil, err
+	}
+
+	sort.Strings(notes)
+	return fields, notes, nil
+}
+
+// encodeNetwork writes the fields of a network file in the order of the JNetwork
+// struct using the current field names. Only the fields in the file are written so
+// a file that uses a preset still only changes the fields it sets
+func encodeNetwork(fields map[string]json.RawMessage) ([]byte, error) {
+
+	var b bytes.Buffer
+	b.WriteString("{")
+	done := make(map[string]bool)
+	t := reflect.TypeOf(JNetwork{})
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		for k, v := range fields {
+			if strings.EqualFold(k, name) == false {
+				continue
+			}
+			if len(done) > 0 {
+				b.WriteString(",")
+			}
+			fmt.Fprintf(&b, "\n %q: ", name)
+			if err := json.Indent(&b, v, " ", " "); err != nil {
+				return nil, err
+			}
+			done[k] = true
+		}
+	}
+	var unknown []string
+	for k := range fields {
+		if done[k] == false {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("Unknown fields %v", unknown)
+	}
+	b.WriteString("\n}\n")
+	return b.Bytes(), nil
+}
+
+// strictDecode decodes a network file and errors on any field that is not in JNetwork
+func strictDecode(data []byte, jnw *JNetwork) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(jnw)
+}
+
+// runMigrate is the migrate-config subcommand. It rewrites network files that use
+// the older field names into the current format
+func runMigrate(args []string) error {
+
+	fs := flag.NewFlagSet("migrate-config", flag.ExitOnError)
+	dryRun := fs.Bool("n", false, "Write the converted files to stdout and leave the files unchanged")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s migrate-config [-n] file ...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("Error - migrate-config needs at least one network file")
+	}
+
+	for _, fName := range fs.Args() {
+		data, err := ioutil.ReadFile(fName)
+		if err != nil {
+			return fmt.Errorf("Error reading network file: %v", err)
+		}
+		fields, notes, err := migrateNetwork(data)
+		if err != nil {
+			return fmt.Errorf("Error converting %s - %v", fName, err)
+		}
+		j, err := encodeNetwork(fields)
+		if err == nil {
+			err = strictDecode(j, &JNetwork{})
+		}
+		if err != nil {
+			return fmt.Errorf("Error converting %s - %v", fName, err)
+		}
+
+		if *dryRun {
+			if fs.NArg() > 1 {
+				fmt.Printf("# %s\n", fName)
+			}
+			fmt.Printf("%s", j)
+			continue
+		}
+		if len(notes) == 0 {
+			fmt.Printf("%s: already in the current format\n", fName)
+			continue
+		}
+		tmp := fName + ".tmp"
+		if err = ioutil.WriteFile(tmp, j, 0644); err != nil {
+			return fmt.Errorf("Error writing %s - %v", tmp, err)
+		}
+		if err = os.Rename(tmp, fName); err != nil {
+			return fmt.Errorf("Error replacing %s - %v", fName, err)
+		}
+		fmt.Printf("%s: converted %s\n", fName, strings.Join(notes, ", "))
+	}
+	return nil
+}
+
+/*
+
+ */
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// legacyPKT is the pkt network file in the format used before InitialIPs and Seeders
+const legacyPKT = `{
+ "Name": "PKT",
+ "Desc": "pkt.cash mainnet",
+ "ID": "0x082f00fc",
+ "Port": 64764,
+ "Pver": 70001,
+ "DNSName": "seed.pkt.ai",
+ "TTL": 600,
+ "InitialIP": "1.2.3.4, 5.6.7.8",
+ "Seeder2": "pktdseed.pkt.world",
+ "Seeder1": "seed.cjd.li"
+}`
+
+func TestDecodeLegacyNetwork(t *testing.T) {
+
+	jnw, notes, err := decodeNetwork([]byte(legacyPKT))
+	if err != nil {
+		t.Fatalf("unable to decode legacy file: %v", err)
+	}
+	if strings.Join(jnw.InitialIPs, " ") != "1.2.3.4 5.6.7.8" || strings.Join(jnw.Seeders, " ") != "seed.cjd.li pktdseed.pkt.world" {
+		t.Errorf("legacy file decoded as InitialIPs: %v Seeders: %v", jnw.InitialIPs, jnw.Seeders)
+	}
+	if len(notes) != 3 {
+		t.Errorf("notes: %v expected 3", notes)
+	}
+
+	// old seeders are added after the current ones
+	jnw, _, err = decodeNetwork([]byte(`{"Seeders": ["a.example.com"], "Seeder1": "b.example.com"}`))
+	if err != nil || strings.Join(jnw.Seeders, " ") != "a.example.com b.example.com" {
+		t.Errorf("merged seeders: %v %v", jnw.Seeders, err)
+	}
+
+	var bad = []string{
+		`{"Name": "Net", "Seedr": "typo.example.com"}`,
+		`{"Name": "Net", "InitialIP": ["1.2.3.4"]}`,
+		`{"Preset": "bitcoin-main", "DNSNmae": "seed.example.com"}`,
+		`{"Name": "Net"`,
+	}
+	for _, j := range bad {
+		if _, _, err = decodeNetwork([]byte(j)); err == nil {
+			t.Errorf("bad network file accepted: %s", j)
+		}
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fName := filepath.Join(dir, "pkt.json")
+	if err = ioutil.WriteFile(fName, []byte(legacyPKT), 0644); err != nil {
+		t.Fatal(err)
+	}
+	preset := filepath.Join(dir, "main.json")
+	if err = ioutil.WriteFile(preset, []byte(`{"dnsname": "seed.example.com", "preset": "bitcoin-main", "seeder1": "seed.example.net"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = runMigrate([]string{fName, preset}); err != nil {
+		t.Fatalf("migrate-config failed: %v", err)
+	}
+
+	j, err := ioutil.ReadFile(fName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(j), "Seeder1") || strings.Contains(string(j), "InitialIP\"") {
+		t.Errorf("old fields left in migrated file:\n%s", j)
+	}
+	var jnw JNetwork
+	if err = strictDecode(j, &jnw); err != nil || len(jnw.Seeders) != 2 || jnw.Port != 64764 {
+		t.Errorf("migrated file: %v %+v", err, jnw)
+	}
+
+	// a preset file keeps only its own fields in struct order
+	j, err = ioutil.ReadFile(preset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n \"Preset\": \"bitcoin-main\",\n \"DNSName\": \"seed.example.com\",\n \"Seeders\": [\n  \"seed.example.net\"\n ]\n}\n"
+	if string(j) != want {
+		t.Errorf("migrated preset file:\n%s\nexpected:\n%s", j, want)
+	}
+	if s, err := loadNetwork(preset); err != nil || s.port != 8333 || len(s.seeders) != 1 {
+		t.Errorf("migrated preset file does not load: %v", err)
+	}
+
+	// unknown fields are not written
+	if err = ioutil.WriteFile(fName, []byte(`{"Name": "Net", "Colour": "blue"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = runMigrate([]string{fName}); err == nil {
+		t.Errorf("file with unknown fields migrated")
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defMaxPerGroup    = 100 // default max nodes from one netgroup in theList
+	defMaxDNSPerGroup = 2   // default max nodes from one netgroup in one dns answer
+)
+
+// asMap maps address prefixes to the autonomous system that announces them
+type asMap struct {
+	prefixes map[int]map[string]uint32 // prefix length -> masked ip -> asn
+	lengths  []int                     // prefix lengths in the map, longest first
+	entries  int
+}
+
+// loadASMap reads an asmap file. Each line holds a CIDR prefix and an ASN such as
+// "1.2.0.0/16 AS1234" or "2001:db8::/32 1234". Blank lines and lines starting with # are ignored
+func loadASMap(fName string) (*asMap, error) {
+
+	f, err := os.Open(fName)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening asmap file: %v", err)
+	}
+	defer f.Close()
+
+	am := &asMap{prefixes: make(map[int]map[string]uint32)}
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		l := strings.TrimSpace(scanner.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		fields := strings.Fields(l)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Error in asmap file line %v: expected prefix and ASN", line)
+		}
+		_, ipnet, err := net.ParseCIDR(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("Error in asmap file line %v: %v", line, err)
+		}
+		asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(fields[1]), "AS"), 10, 32)
+		if err != nil || asn == 0 {
+			return nil, fmt.Errorf("Error in asmap file line %v: invalid ASN %s", line, fields[1])
+		}
+
+		// store all prefixes as 16 byte addresses so v4 and v6 share the lookup
+		ones, bits := ipnet.Mask.Size()
+		if bits == 32 {
+			ones += 96
+		}
+		if _, ok := am.prefixes[ones]; ok == false {
+			am.prefixes[ones] = make(map[string]uint32)
+			am.lengths = append(am.lengths, ones)
+		}
+		am.prefixes[ones][string(ipnet.IP.To16().Mask(net.CIDRMask(ones, 128)))] = uint32(asn)
+		am.entries++
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error reading asmap file: %v", err)
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(am.lengths)))
+	return am, nil
+}
+
+// lookup returns the ASN for the longest prefix that contains ip or 0 if not found
+func (am *asMap) lookup(ip net.IP) uint32 {
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return 0
+	}
+	for _, l := range am.lengths {
+		if asn, ok := am.prefixes[l][string(ip16.Mask(net.CIDRMask(l, 128)))]; ok {
+			return asn
+		}
+	}
+	return 0
+}
+
+// netGroup returns the name of the group an address belongs to for diversity limits.
+// This is the ASN if an asmap is loaded and has the address, otherwise the /16 for
+// ipv4 or the /32 for ipv6
+func netGroup(ip net.IP) string {
+	if config.asmap != nil {
+		if asn := config.asmap.lookup(ip); asn != 0 {
+			return "AS" + strconv.FormatUint(uint64(asn), 10)
+		}
+	}
+	if x := ip.To4(); x != nil {
+		return x.Mask(net.CIDRMask(16, 32)).String() + "/16"
+	}
+	return ip.Mask(net.CIDRMask(32, 128)).String() + "/32"
+}
+
+/*
+
+ */
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/miekg/dns"
+)
+
+func TestNetGroup(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fName := filepath.Join(dir, "asmap.txt")
+	asmap := "# test asmap\n1.2.0.0/16 AS100\n1.2.3.0/24 200\n\n2001:db8::/32 AS300\n"
+	if err = ioutil.WriteFile(fName, []byte(asmap), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		ip      string
+		group   string
+		asGroup string
+	}{
+		{"1.2.3.4", "1.2.0.0/16", "AS200"},
+		{"1.2.4.4", "1.2.0.0/16", "AS100"},
+		{"5.6.7.8", "5.6.0.0/16", "5.6.0.0/16"},
+		{"2001:db8:1::1", "2001:db8::/32", "AS300"},
+		{"2a01:4f8:1::1", "2a01:4f8::/32", "2a01:4f8::/32"},
+	}
+
+	for _, tt := range tests {
+		if g := netGroup(net.ParseIP(tt.ip)); g != tt.group {
+			t.Errorf("ip: %s group: %s expected: %s", tt.ip, g, tt.group)
+		}
+	}
+
+	am, err := loadASMap(fName)
+	if err != nil {
+		t.Fatalf("unable to load asmap: %v", err)
+	}
+	config.asmap = am
+	defer func() { config.asmap = nil }()
+
+	for _, tt := range tests {
+		if g := netGroup(net.ParseIP(tt.ip)); g != tt.asGroup {
+			t.Errorf("ip: %s asmap group: %s expected: %s", tt.ip, g, tt.asGroup)
+		}
+	}
+
+	if err = ioutil.WriteFile(fName, []byte("1.2.0.0/16\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = loadASMap(fName); err == nil {
+		t.Errorf("asmap line without an ASN was accepted")
+	}
+}
+
+func TestGroupLimits(t *testing.T) {
+
+	s := &dnsseeder{
+		port:         8333,
+		maxSize:      1250,
+		maxPerGroup:  3,
+		maxDNSPerGrp: 2,
+		dnsHost:      "seed.group.test",
+		dnsNames:     []dnsName{{host: "seed.group.test"}},
+		name:         "GroupNet",
+		ttl:          60,
+		allowPrivate: true,
+	}
+	s.theList = make(map[nodeAddr]*node)
+	s.counts.DNSCounts = make([]uint32, maxDNSTypes)
+
+	// only maxPerGroup nodes from 10.1.0.0/16 should be accepted
+	added := 0
+	for i := 1; i <= 5; i++ {
+		if s.addNode(wire.NewNetAddressIPPort(net.ParseIP(fmt.Sprintf("10.1.0.%d", i)), 8333, 0), "") != nil {
+			added++
+		}
+	}
+	if added != 3 {
+		t.Errorf("added %v nodes from one netgroup expected: 3", added)
+	}
+	s.addNode(wire.NewNetAddressIPPort(net.ParseIP("10.2.0.1"), 8333, 0), "")
+	if len(s.groupCounts) != 2 {
+		t.Errorf("netgroups: %v expected: 2", len(s.groupCounts))
+	}
+
+	// removing a node frees up space in its group
+	s.removeNode(mustAddr("10.1.0.1:8333"))
+	if s.addNode(wire.NewNetAddressIPPort(net.ParseIP("10.1.0.9"), 8333, 0), "") == nil {
+		t.Errorf("node not added after a node from its group was removed")
+	}
+
+	// only maxDNSPerGrp nodes from one group should be in the dns answer
+	for _, nd := range s.theList {
+		nd.status = statusCG
+		nd.lastConnect = time.Now()
+	}
+	config.dns = make(map[string][]dns.RR)
+	updateDNS(s)
+
+	groups := make(map[string]int)
+	for _, rr := range config.dns[s.dnsHost+".A"] {
+		groups[netGroup(rr.(*dns.A).A)]++
+	}
+	if groups["10.1.0.0/16"] != 2 || groups["10.2.0.0/16"] != 1 {
+		t.Errorf("dns answer netgroups: %v expected 2 from 10.1.0.0/16 and 1 from 10.2.0.0/16", groups)
+	}
+}
+
+/*
+
+ */
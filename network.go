@@ -3,23 +3,52 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/btcsuite/btcd/wire"
+	"io/ioutil"
 	"log"
+	"net"
 	"os"
-	"strconv"
+	"strings"
 )
 
 // JNetwork is the exported struct that is read from the network file
 type JNetwork struct {
-	Name       string
-	Desc       string
-	ID         string
-	Port       uint16
-	Pver       uint32
-	DNSName    string
-	TTL        uint32
-	InitialIPs []string
-	Seeders    []string
+	Preset          string `json:",omitempty"`
+	Name            string
+	Desc            string
+	ID              string
+	Port            uint16
+	Pver            uint32
+	DNSName         JDNSNames
+	TTL             uint32
+	MaxCrawls       int `json:",omitempty"`
+	InitialIPs      []string
+	Seeders         []string
+	Checkpoints     []JCheckpoint `json:",omitempty"`
+	MinChainWork    string        `json:",omitempty"`
+	ProbeInterval   int           `json:",omitempty"`
+	ProbeTimeout    int           `json:",omitempty"`
+	BindIPv4        string        `json:",omitempty"`
+	BindIPv6        string        `json:",omitempty"`
+	DisableIPv4     bool          `json:",omitempty"`
+	DisableIPv6     bool          `json:",omitempty"`
+	AllowPrivate    bool          `json:",omitempty"`
+	MaxPerGroup     int           `json:",omitempty"`
+	MaxDNSPerGroup  int           `json:",omitempty"`
+	MaxStart        []uint32      `json:",omitempty"`
+	Delay           []int64       `json:",omitempty"`
+	Backoff         []int64       `json:",omitempty"`
+	MaxSize         int           `json:",omitempty"`
+	CrawlDelay      int           `json:",omitempty"`
+	AuditDelay      int           `json:",omitempty"`
+	DNSDelay        int           `json:",omitempty"`
+	MaxFails        int           `json:",omitempty"`
+	MaxTo           int           `json:",omitempty"`
+	EvictPolicy     string        `json:",omitempty"`
+	MinGood         int           `json:",omitempty"`
+	RPC             *JRPC         `json:",omitempty"`
+	Codec           string        `json:",omitempty"`
+	Checksum        string        `json:",omitempty"`
+	SignetChallenge string        `json:",omitempty"`
 }
 
 func createNetFile() {
@@ -27,18 +56,18 @@ func createNetFile() {
 
 	// create a struct to encode with json
 	jnw := &JNetwork{
-		ID:         "0xabcdef01",
-		Port:       1234,
-		Pver:       70001,
-		TTL:        600,
-		DNSName:    "seeder.example.com",
-		Name:       "SeederNet",
-		Desc:       "Description of SeederNet",
+		ID:      "0xabcdef01",
+		Port:    1234,
+		Pver:    70001,
+		TTL:     600,
+		DNSName: newDNSNames("seeder.example.com"),
+		Name:    "SeederNet",
+		Desc:    "Description of SeederNet",
 		InitialIPs: []string{
 			"0.0.0.0",
 			"0.0.0.0",
 		},
-		Seeders:    []string{
+		Seeders: []string{
 			"seeder1.example.com",
 			"seed1.bob.com",
 			"seed2.example.com",
@@ -62,18 +91,24 @@ func createNetFile() {
 }
 
 func loadNetwork(fName string) (*dnsseeder, error) {
-	nwFile, err := os.Open(fName)
+	data, err := ioutil.ReadFile(fName)
 	if err != nil {
 		return nil, fmt.Errorf("Error reading network file: %v", err)
 	}
+	return loadNetworkData(fName, data)
+}
 
-	defer nwFile.Close()
-
-	var jnw JNetwork
+// loadNetworkData creates a seeder from the json of a network. fName is the network
+// file or where the network came from for the error messages
+func loadNetworkData(fName string, data []byte) (*dnsseeder, error) {
 
-	jsonParser := json.NewDecoder(nwFile)
-	if err = jsonParser.Decode(&jnw); err != nil {
-		return nil, fmt.Errorf("Error decoding network file: %v", err)
+	jnw, notes, err := decodeNetwork(data)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding network file %s: %v", fName, err)
+	}
+	if len(notes) > 0 {
+		log.Printf("Warning - network file %s uses old field names. Converted %s. Run %s migrate-config %s to update it\n",
+			fName, strings.Join(notes, ", "), os.Args[0], fName)
 	}
 
 	return initNetwork(jnw)
@@ -86,51 +121,122 @@ func initNetwork(jnw JNetwork) (*dnsseeder, error) {
 
 	}
 
-	if jnw.DNSName == "" {
-		return nil, fmt.Errorf("No DNS Hostname supplied")
+	// every name is served from the same nodes. The first is used when we need just one
+	dnsNames, err := loadDNSNames(jnw.DNSName)
+	if err != nil {
+		return nil, err
 	}
 
 	// init the seeder
 	seeder := &dnsseeder{}
-	seeder.theList = make(map[string]*node)
+	seeder.theList = make(map[nodeAddr]*node)
 	seeder.port = jnw.Port
 	seeder.pver = jnw.Pver
 	seeder.ttl = jnw.TTL
 	seeder.name = jnw.Name
 	seeder.desc = jnw.Desc
-	seeder.dnsHost = jnw.DNSName
+	seeder.dnsHost = dnsNames[0].host
+	seeder.dnsNames = dnsNames
+	seeder.resolver = netResolver{}
+	seeder.stop = make(chan struct{})
+	seeder.stopped = make(chan struct{})
+	seeder.tables.key = newSecret()
+	seeder.updated = make(chan struct{}, 1)
+
+	// local addresses and address families to use when crawling
+	if jnw.BindIPv4 != "" {
+		if seeder.bindIPv4 = net.ParseIP(jnw.BindIPv4); seeder.bindIPv4 == nil || seeder.bindIPv4.To4() == nil {
+			return nil, fmt.Errorf("Invalid BindIPv4 address: %s", jnw.BindIPv4)
+		}
+	}
+	if jnw.BindIPv6 != "" {
+		if seeder.bindIPv6 = net.ParseIP(jnw.BindIPv6); seeder.bindIPv6 == nil || seeder.bindIPv6.To4() != nil {
+			return nil, fmt.Errorf("Invalid BindIPv6 address: %s", jnw.BindIPv6)
+		}
+	}
+	if jnw.DisableIPv4 && jnw.DisableIPv6 {
+		return nil, fmt.Errorf("Can not disable both ipv4 and ipv6")
+	}
+	seeder.disableIPv4 = jnw.DisableIPv4
+	seeder.disableIPv6 = jnw.DisableIPv6
+	seeder.dialer = newNetDialer(seeder.bindIPv4, seeder.bindIPv6)
 
-	// conver the network magic number to a Uint32
-	t1, err := strconv.ParseUint(jnw.ID, 0, 32)
+	// conver the network magic number to a Uint32. Signets derive it from the challenge
+	id, err := networkMagic(jnw)
 	if err != nil {
-		return nil, fmt.Errorf("Error converting Network Magic number: %v", err)
+		return nil, err
+	}
+	seeder.id = id
+
+	// message framing for networks that do not use the bitcoin checksum
+	if seeder.codec, err = newCodec(jnw.Codec, jnw.Checksum); err != nil {
+		return nil, err
+	}
+
+	// load the checkpoints used to make sure nodes are on the correct chain
+	if err = seeder.loadCheckpoints(jnw.Checkpoints, jnw.MinChainWork); err != nil {
+		return nil, err
+	}
+
+	// block probes start from a random checkpoint so they need at least one
+	if jnw.ProbeInterval > 0 && len(seeder.checkpoints) == 0 {
+		return nil, fmt.Errorf("ProbeInterval needs at least one checkpoint")
+	}
+	seeder.probeInterval = jnw.ProbeInterval
+	seeder.probeTimeout = jnw.ProbeTimeout
+	if seeder.probeTimeout <= 0 {
+		seeder.probeTimeout = defProbeTimeout
 	}
-	seeder.id = wire.BitcoinNet(t1)
 
 	seeder.initialIPs = jnw.InitialIPs
 
 	// load the seeder dns
 	seeder.seeders = jnw.Seeders
 
-	// add some checks to the start & delay values to keep them sane
-	seeder.maxStart = []uint32{20, 20, 20, 30}
-	seeder.delay = []int64{210, 789, 234, 1876}
-	seeder.maxSize = 1250
+	// a trusted local node to ask for addresses as well as the other seeders
+	if jnw.RPC != nil {
+		if seeder.rpc, err = newRPCSource(*jnw.RPC); err != nil {
+			return nil, err
+		}
+	}
+
+	// crawl and audit policy with checks to keep the values sane
+	if err := seeder.loadPolicy(jnw); err != nil {
+		return nil, err
+	}
+
+	seeder.maxCrawls = jnw.MaxCrawls
+	if seeder.maxCrawls <= 0 {
+		seeder.maxCrawls = defMaxCrawls
+	}
+
+	// netgroup diversity limits
+	if jnw.MaxPerGroup < 0 || jnw.MaxDNSPerGroup < 0 {
+		return nil, fmt.Errorf("MaxPerGroup and MaxDNSPerGroup can not be negative")
+	}
+	seeder.groupCounts = make(map[string]int)
+	seeder.srcCounts = make(map[string]int)
+	seeder.allowPrivate = jnw.AllowPrivate
+	seeder.maxPerGroup = jnw.MaxPerGroup
+	if seeder.maxPerGroup == 0 {
+		seeder.maxPerGroup = defMaxPerGroup
+	}
+	seeder.maxDNSPerGrp = jnw.MaxDNSPerGroup
+	if seeder.maxDNSPerGrp == 0 {
+		seeder.maxDNSPerGrp = defMaxDNSPerGroup
+	}
 
 	// initialize the stats counters
 	seeder.counts.NdStatus = make([]uint32, maxStatusTypes)
 	seeder.counts.NdStarts = make([]uint32, maxStatusTypes)
 	seeder.counts.DNSCounts = make([]uint32, maxDNSTypes)
+	seeder.counts.NameCounts = make(map[string]uint32)
 
 	// some sanity checks on the loaded config options
 	if seeder.ttl < 60 {
 		seeder.ttl = 60
 	}
 
-	if dup, err := isDuplicateSeeder(seeder); dup == true {
-		return nil, err
-	}
-
 	return seeder, nil
 }
 
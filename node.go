@@ -9,21 +9,34 @@ import (
 
 // Node struct contains details on one client
 type node struct {
-	na           *wire.NetAddress // holds ip address & port details
-	lastConnect  time.Time        // last time we sucessfully connected to this client
-	lastTry      time.Time        // last time we tried to connect to this client
-	crawlStart   time.Time        // time when we started the last crawl
-	nonstdIP     net.IP           // if not using the default port then this is the encoded ip containing the actual port
-	statusStr    string           // string with last error or OK details
-	strVersion   string           // remote client user agent
-	services     wire.ServiceFlag // remote client supported services
-	connectFails uint32           // number of times we have failed to connect to this client
-	version      int32            // remote client protocol version
-	lastBlock    int32            // remote client last block
-	status       uint32           // rg,cg,wg,ng
-	rating       uint32           // if it reaches 100 then we mark them statusNG
-	dnsType      uint32           // what dns type this client is
-	crawlActive  bool             // are we currently crawling this client
+	na           *wire.NetAddress         // holds ip address & port details
+	addr         nodeAddr                 // canonical address used as the theList key
+	lastConnect  time.Time                // last time we sucessfully connected to this client
+	lastTry      time.Time                // last time we tried to connect to this client
+	crawlStart   time.Time                // time when we started the last crawl
+	nextCrawl    time.Time                // time when this client is next due to be crawled
+	chainChecked time.Time                // last time we checked this client is on the correct chain
+	probed       time.Time                // last time we probed this client for a historical block
+	nonstdIP     net.IP                   // if not using the default port then this is the encoded ip containing the actual port
+	statusStr    string                   // string with last error or OK details
+	strVersion   string                   // remote client user agent
+	services     wire.ServiceFlag         // remote client supported services
+	connectFails uint32                   // number of times we have failed to connect to this client
+	version      int32                    // remote client protocol version
+	lastBlock    int32                    // remote client last block
+	status       uint32                   // rg,cg,wg,ng
+	rating       uint32                   // if it reaches 100 then we mark them statusNG
+	dnsType      uint32                   // what dns type this client is
+	group        string                   // netgroup used for diversity limits
+	srcGroup     string                   // netgroup of the node or seeder that reported this node
+	table        uint32                   // table the node is in - new or tried
+	bucket       int                      // bucket in the table
+	slot         int                      // slot in the bucket
+	chainStatus  uint32                   // result of the last chain check
+	probeStatus  uint32                   // result of the last block probe
+	qIndex       int                      // position in the crawl queue or -1 if not queued
+	stats        [maxStatWindows]addrStat // decayed crawl results used for the uptime figures
+	crawlActive  bool                     // are we currently crawling this client
 }
 
 // dns2str will return the string description of the dns type
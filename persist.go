@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	snapVersion = 1  // version of the node snapshot file format
+	snapDelay   = 10 // minutes between node snapshots
+)
+
+// snapshot is the on disk format of the node list for one seeder
+type snapshot struct {
+	Version int
+	Network string
+	Magic   uint32
+	Saved   time.Time
+	Nodes   []snapNode
+}
+
+// snapNode holds the details of one node that survive a restart
+type snapNode struct {
+	IP           string
+	Port         uint16
+	Timestamp    time.Time
+	Status       uint32
+	Rating       uint32
+	ConnectFails uint32
+	LastConnect  time.Time
+	LastTry      time.Time
+	Services     uint64
+	Version      int32
+	StrVersion   string
+	LastBlock    int32
+	ChainStatus  uint32    `json:",omitempty"`
+	ChainChecked time.Time `json:",omitempty"`
+	ProbeStatus  uint32    `json:",omitempty"`
+	Probed       time.Time `json:",omitempty"`
+	Stats        [maxStatWindows]addrStat
+	Source       string `json:",omitempty"`
+}
+
+// snapFile returns the name of the snapshot file for this seeder or an empty
+// string if snapshots are not enabled
+func (s *dnsseeder) snapFile() string {
+	if config.datadir == "" {
+		return ""
+	}
+	return filepath.Join(config.datadir, s.name+".nodes.json")
+}
+
+// saveNodes writes a snapshot of theList to disk. The snapshot is written to a
+// temporary file first so a crash will never leave a partial snapshot
+func (s *dnsseeder) saveNodes() error {
+
+	fName := s.snapFile()
+	if fName == "" {
+		return nil
+	}
+
+	snap := snapshot{
+		Version: snapVersion,
+		Network: s.name,
+		Magic:   uint32(s.id),
+		Saved:   time.Now(),
+	}
+
+	s.mtx.RLock()
+	snap.Nodes = make([]snapNode, 0, len(s.theList))
+	for _, nd := range s.theList {
+		snap.Nodes = append(snap.Nodes, snapNode{
+			IP:           nd.addr.IP().String(),
+			Port:         nd.addr.Port(),
+			Timestamp:    nd.na.Timestamp,
+			Status:       nd.status,
+			Rating:       nd.rating,
+			ConnectFails: nd.connectFails,
+			LastConnect:  nd.lastConnect,
+			LastTry:      nd.lastTry,
+			Services:     uint64(nd.services),
+			Version:      nd.version,
+			StrVersion:   nd.strVersion,
+			LastBlock:    nd.lastBlock,
+			ChainStatus:  nd.chainStatus,
+			ChainChecked: nd.chainChecked,
+			ProbeStatus:  nd.probeStatus,
+			Probed:       nd.probed,
+			Stats:        nd.stats,
+			Source:       nd.srcGroup,
+		})
+	}
+	s.mtx.RUnlock()
+
+	j, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("Error encoding snapshot: %v", err)
+	}
+
+	tmp := fName + ".tmp"
+	if err = ioutil.WriteFile(tmp, j, 0644); err != nil {
+		return fmt.Errorf("Error writing snapshot: %v", err)
+	}
+	if err = os.Rename(tmp, fName); err != nil {
+		return fmt.Errorf("Error replacing snapshot: %v", err)
+	}
+
+	if config.verbose {
+		log.Printf("%s: saved %v nodes to %s\n", s.name, len(snap.Nodes), fName)
+	}
+	return nil
+}
+
+// loadNodes reads the snapshot for this seeder and adds the nodes to theList
+// with the state they had when it was saved. The nodes have already been checked so
+// the netgroup, source and size limits for new addresses are not applied. It returns
+// the number of nodes loaded
+func (s *dnsseeder) loadNodes() (int, error) {
+
+	fName := s.snapFile()
+	if fName == "" {
+		return 0, nil
+	}
+
+	j, err := ioutil.ReadFile(fName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("Error reading snapshot: %v", err)
+	}
+
+	var snap snapshot
+	if err = json.Unmarshal(j, &snap); err != nil {
+		return 0, fmt.Errorf("Error decoding snapshot %s: %v", fName, err)
+	}
+	if snap.Version < 1 || snap.Version > snapVersion {
+		return 0, fmt.Errorf("Snapshot %s has unsupported version %v", fName, snap.Version)
+	}
+	if wire.BitcoinNet(snap.Magic) != s.id {
+		return 0, fmt.Errorf("Snapshot %s is for a different network. Magic: %s", fName, wire.BitcoinNet(snap.Magic))
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	c, dropped := 0, 0
+	for _, sn := range snap.Nodes {
+		ip := net.ParseIP(sn.IP)
+		if ip == nil || sn.Status >= maxStatusTypes {
+			dropped++
+			continue
+		}
+
+		// only drop nodes the current config says we must not crawl
+		na := wire.NewNetAddressTimestamp(sn.Timestamp, wire.ServiceFlag(sn.Services), ip, sn.Port)
+		if _, dup := s.theList[naAddr(na)]; dup == true || s.allowAddr(na) == false {
+			dropped++
+			continue
+		}
+
+		nd := s.newNode(na, sn.Source)
+		nd.status = sn.Status
+		nd.rating = sn.Rating
+		nd.connectFails = sn.ConnectFails
+		nd.lastConnect = sn.LastConnect
+		nd.lastTry = sn.LastTry
+		nd.services = wire.ServiceFlag(sn.Services)
+		nd.version = sn.Version
+		nd.strVersion = sn.StrVersion
+		nd.lastBlock = sn.LastBlock
+		nd.chainStatus = sn.ChainStatus
+		nd.chainChecked = sn.ChainChecked
+		nd.probeStatus = sn.ProbeStatus
+		nd.probed = sn.Probed
+		nd.stats = sn.Stats
+		nd.statusStr = "loaded from snapshot"
+
+		s.insertNode(nd)
+		s.restoreSlot(nd)
+
+		// carry on crawling the node when it would have been due
+		s.scheduleNode(nd)
+		c++
+	}
+
+	log.Printf("%s: loaded %v nodes from snapshot %s saved %s ago\n", s.name, c, fName, time.Since(snap.Saved).String())
+	if dropped > 0 {
+		log.Printf("%s: dropped %v nodes from snapshot %s that are invalid or not allowed by the config\n", s.name, dropped, fName)
+	}
+	return c, nil
+}
+
+/*
+
+ */
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/gombadi/dnsseeder/simpeer"
+)
+
+func TestSnapshot(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(d string) { config.datadir = d }(config.datadir)
+	config.datadir = dir
+
+	s := newSimSeeder(t, simpeer.NewNetwork(), simNetwork())
+	s.addNa(simAddr("1.2.3.4", 8333), "")
+	s.addNa(simAddr("2a01:4f8::1", 18333), "")
+
+	nd := s.theList[mustAddr("1.2.3.4:8333")]
+	s.setStatus(nd, statusCG)
+	nd.lastTry = time.Now().Add(-time.Minute)
+	nd.lastConnect = nd.lastTry
+	nd.services = wire.SFNodeNetwork | wire.SFNodeWitness
+	nd.version = 70015
+	nd.strVersion = "/Satoshi:0.20.1/"
+	nd.lastBlock = 650000
+	nd.connectFails = 0
+	nd.rating = 25
+
+	if err = s.saveNodes(); err != nil {
+		t.Fatalf("unable to save snapshot: %v", err)
+	}
+
+	// a new seeder for the same network loads the snapshot instead of asking the seeders
+	ns := newSimSeeder(t, simpeer.NewNetwork(), simNetwork("seed.example.com"))
+	ns.initSeeder()
+
+	if len(ns.theList) != 2 {
+		t.Fatalf("loaded nodes: %v expected: 2", len(ns.theList))
+	}
+	lnd := ns.theList[mustAddr("1.2.3.4:8333")]
+	if lnd.status != statusCG || lnd.rating != 25 || lnd.services != nd.services || lnd.version != 70015 ||
+		lnd.strVersion != nd.strVersion || lnd.lastBlock != 650000 || lnd.lastTry.Equal(nd.lastTry) == false {
+		t.Errorf("loaded node does not match saved node: %+v", lnd)
+	}
+	if ns.statusCount[statusCG] != 1 || ns.statusCount[statusRG] != 1 {
+		t.Errorf("status counts CG:RG %v:%v expected: 1:1", ns.statusCount[statusCG], ns.statusCount[statusRG])
+	}
+	if lnd.qIndex < 0 || lnd.nextCrawl.Before(lnd.lastTry) {
+		t.Errorf("loaded node not scheduled after its last try")
+	}
+	if ns.theList[mustAddr("[2a01:4f8::1]:18333")].dnsType != dnsV6Non {
+		t.Errorf("loaded ipv6 node has the wrong dns type")
+	}
+
+	// a snapshot for a different network must not be loaded
+	jnw := simNetwork()
+	jnw.ID = "0xd9b4bef9"
+	other := newSimSeeder(t, simpeer.NewNetwork(), jnw)
+	if n, err := other.loadNodes(); n != 0 || err == nil {
+		t.Errorf("loaded %v nodes from another network snapshot err: %v", n, err)
+	}
+
+	// and neither must an unknown version
+	ioutil.WriteFile(filepath.Join(dir, "SimNet.nodes.json"), []byte(`{"Version": 99, "Magic": 118034699}`), 0644)
+	if n, err := ns.loadNodes(); n != 0 || err == nil {
+		t.Errorf("loaded %v nodes from unknown snapshot version err: %v", n, err)
+	}
+}
+
+func TestSnapshotKeepsGood(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(d string) { config.datadir = d }(config.datadir)
+	config.datadir = dir
+
+	// good nodes from one netgroup and one source that the limits for new addresses
+	// would refuse. A warm start must keep every one of them
+	jnw := simNetwork()
+	jnw.MaxSize = 10
+	jnw.MaxPerGroup = 4
+	s := newSimSeeder(t, simpeer.NewNetwork(), jnw)
+	for i := 1; i <= 20; i++ {
+		nd := s.newNode(simAddr(fmt.Sprintf("20.1.%d.1", i), 8333), "6.6.0.0/16")
+		nd.status = statusCG
+		s.insertNode(nd)
+	}
+	if err = s.saveNodes(); err != nil {
+		t.Fatalf("unable to save snapshot: %v", err)
+	}
+
+	ns := newSimSeeder(t, simpeer.NewNetwork(), jnw)
+	if n, err := ns.loadNodes(); n != 20 || err != nil {
+		t.Fatalf("loaded %v nodes err: %v expected: 20", n, err)
+	}
+	if ns.statusCount[statusCG] != 20 || ns.groupCounts["20.1.0.0/16"] != 20 || ns.srcCounts["6.6.0.0/16"] != 20 {
+		t.Errorf("counts after load CG: %v group: %v source: %v expected: 20", ns.statusCount[statusCG],
+			ns.groupCounts["20.1.0.0/16"], ns.srcCounts["6.6.0.0/16"])
+	}
+	if ns.tables.nTried+ns.tables.nNew > 20 || ns.tables.nTried == 0 {
+		t.Errorf("table counts after load tried: %v new: %v", ns.tables.nTried, ns.tables.nNew)
+	}
+
+	// a node the config no longer allows is still dropped
+	jnw.DisableIPv4 = true
+	other := newSimSeeder(t, simpeer.NewNetwork(), jnw)
+	if n, _ := other.loadNodes(); n != 0 {
+		t.Errorf("loaded %v ipv4 nodes with ipv4 disabled", n)
+	}
+}
+
+/*
+
+ */
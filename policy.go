@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+)
+
+// default crawl and audit policy used when the network file does not set a value
+const (
+	defCrawlDelay = 22   // seconds between start crawler ticks
+	defAuditDelay = 22   // minutes between audit channel ticks
+	defDNSDelay   = 57   // seconds between updates to active dns record list
+	defMaxFails   = 58   // max number of connect fails before we delete a node. Just over 24 hours(checked every 33 minutes)
+	defMaxTo      = 250  // max seconds (4min 10 sec) for all comms to node to complete before we timeout
+	defMaxSize    = 1250 // max number of clients before we start restricting new entries
+)
+
+var (
+	defMaxStart = []uint32{20, 20, 20, 30}
+	defDelay    = []int64{210, 789, 234, 1876}
+	defBackoff  = []int64{2, 1, 4, 1}
+)
+
+// loadPolicy validates the crawl and audit policy from the network file and
+// adds it to the seeder with defaults for any values not supplied
+func (s *dnsseeder) loadPolicy(jnw JNetwork) error {
+
+	// per status values must have one entry for each of RG, CG, WG & NG
+	s.maxStart = defMaxStart
+	if jnw.MaxStart != nil {
+		if err := checkMaxStart(jnw.MaxStart); err != nil {
+			return err
+		}
+		s.maxStart = jnw.MaxStart
+	}
+
+	s.delay = defDelay
+	if jnw.Delay != nil {
+		if len(jnw.Delay) != maxStatusTypes {
+			return fmt.Errorf("Delay needs %v values, one for each node status", maxStatusTypes)
+		}
+		for _, d := range jnw.Delay {
+			if d <= 0 {
+				return fmt.Errorf("Delay values must be greater than 0 seconds")
+			}
+		}
+		s.delay = jnw.Delay
+	}
+
+	s.backoff = defBackoff
+	if jnw.Backoff != nil {
+		if len(jnw.Backoff) != maxStatusTypes {
+			return fmt.Errorf("Backoff needs %v values, one for each node status", maxStatusTypes)
+		}
+		for _, b := range jnw.Backoff {
+			if b < 1 {
+				return fmt.Errorf("Backoff values must be at least 1")
+			}
+		}
+		s.backoff = jnw.Backoff
+	}
+
+	if jnw.MaxSize < 0 || jnw.CrawlDelay < 0 || jnw.AuditDelay < 0 || jnw.DNSDelay < 0 || jnw.MaxFails < 0 || jnw.MaxTo < 0 || jnw.MinGood < 0 {
+		return fmt.Errorf("MaxSize, CrawlDelay, AuditDelay, DNSDelay, MaxFails, MaxTo and MinGood can not be negative")
+	}
+	s.maxSize = defaultInt(jnw.MaxSize, defMaxSize)
+	s.crawlDelay = defaultInt(jnw.CrawlDelay, defCrawlDelay)
+	s.auditDelay = defaultInt(jnw.AuditDelay, defAuditDelay)
+	s.dnsDelay = defaultInt(jnw.DNSDelay, defDNSDelay)
+	s.maxFails = defaultInt(jnw.MaxFails, defMaxFails)
+	s.maxTo = defaultInt(jnw.MaxTo, defMaxTo)
+	s.minGood = defaultInt(jnw.MinGood, defMinGood)
+
+	switch jnw.EvictPolicy {
+	case "":
+		s.evictPolicy = evictWorst
+	case evictWorst, evictRefuse:
+		s.evictPolicy = jnw.EvictPolicy
+	default:
+		return fmt.Errorf("Invalid EvictPolicy %s. Must be %s or %s", jnw.EvictPolicy, evictWorst, evictRefuse)
+	}
+
+	// a CG node must be crawled at least once between crawler ticks for the audit goal to make sense
+	if s.delay[statusCG] < int64(s.crawlDelay) {
+		return fmt.Errorf("Delay for statusCG (%v) can not be less than CrawlDelay (%v)", s.delay[statusCG], s.crawlDelay)
+	}
+	if s.probeTimeout > s.maxTo {
+		return fmt.Errorf("ProbeTimeout (%v) can not be more than MaxTo (%v)", s.probeTimeout, s.maxTo)
+	}
+	return nil
+}
+
+// checkMaxStart returns an error if the MaxStart values from a network file are not
+// one for each status or would never start a crawl
+func checkMaxStart(maxStart []uint32) error {
+	if len(maxStart) != maxStatusTypes {
+		return fmt.Errorf("MaxStart needs %v values, one for each node status", maxStatusTypes)
+	}
+	for _, m := range maxStart {
+		if m > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("MaxStart values can not all be 0 as no node would ever be crawled")
+}
+
+// defaultInt returns def if v is not set
+func defaultInt(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// policy2str returns the effective crawl and audit policy for display
+func (s *dnsseeder) policy2str() string {
+	return fmt.Sprintf("MaxStart: %v Delay: %v Backoff: %v MaxSize: %v CrawlDelay: %vs AuditDelay: %vm DNSDelay: %vs MaxFails: %v MaxTo: %vs EvictPolicy: %s MinGood: %v",
+		s.maxStart, s.delay, s.backoff, s.maxSize, s.crawlDelay, s.auditDelay, s.dnsDelay, s.maxFails, s.maxTo, s.evictPolicy, s.minGood)
+}
+
+/*
+
+ */
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadPolicy(t *testing.T) {
+
+	// defaults when nothing is set
+	s, err := initNetwork(simNetwork())
+	if err != nil {
+		t.Fatalf("unable to create seeder: %v", err)
+	}
+	if reflect.DeepEqual(s.maxStart, defMaxStart) == false || reflect.DeepEqual(s.delay, defDelay) == false ||
+		s.maxSize != defMaxSize || s.crawlDelay != defCrawlDelay || s.maxTo != defMaxTo {
+		t.Errorf("default policy not applied: %s", s.policy2str())
+	}
+
+	// values from the network file
+	jnw := simNetwork()
+	jnw.ID = "0x0709110c"
+	jnw.MaxStart = []uint32{5, 10, 5, 0}
+	jnw.Delay = []int64{60, 120, 60, 600}
+	jnw.MaxSize = 50000
+	jnw.CrawlDelay = 10
+	jnw.MaxFails = 10
+	jnw.MaxTo = 60
+	if s, err = initNetwork(jnw); err != nil {
+		t.Fatalf("unable to create seeder: %v", err)
+	}
+	if s.maxStart[statusCG] != 10 || s.delay[statusNG] != 600 || s.maxSize != 50000 || s.crawlDelay != 10 ||
+		s.maxFails != 10 || s.maxTo != 60 || s.auditDelay != defAuditDelay {
+		t.Errorf("network policy not applied: %s", s.policy2str())
+	}
+
+	var bad = []struct {
+		desc string
+		set  func(*JNetwork)
+	}{
+		{"short MaxStart", func(j *JNetwork) { j.MaxStart = []uint32{1, 2, 3} }},
+		{"zero MaxStart", func(j *JNetwork) { j.MaxStart = []uint32{0, 0, 0, 0} }},
+		{"zero Delay", func(j *JNetwork) { j.Delay = []int64{60, 0, 60, 60} }},
+		{"zero Backoff", func(j *JNetwork) { j.Backoff = []int64{1, 0, 1, 1} }},
+		{"negative MaxSize", func(j *JNetwork) { j.MaxSize = -1 }},
+		{"negative MaxTo", func(j *JNetwork) { j.MaxTo = -10 }},
+		{"CG delay below CrawlDelay", func(j *JNetwork) { j.CrawlDelay = 1000 }},
+		{"ProbeTimeout above MaxTo", func(j *JNetwork) { j.MaxTo = 10 }},
+	}
+
+	for i, tt := range bad {
+		jnw := simNetwork()
+		jnw.ID = "0x0709120" + string(rune('0'+i))
+		tt.set(&jnw)
+		if _, err := initNetwork(jnw); err == nil {
+			t.Errorf("%s: network loaded but should have failed", tt.desc)
+		}
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// defSignetChallenge is the challenge script of the default bitcoin signet
+const defSignetChallenge = "512103ad5e0edad18cb1f0fc0d28a3d4f1f3e445640337489abb10404f2d1e086be430210359ef5021964fe22d6f8e05b2463c9540ce96883fe3b278760f048f5189f2e6c452ae"
+
+// presets holds the known networks. A network file can name one in Preset and only
+// set the fields that are different, such as DNSName. The DNSName is left out
+// as it belongs to the operator of the seeder
+var presets = map[string]JNetwork{
+	"bitcoin-main": {
+		Name: "BitcoinNet",
+		Desc: "Bitcoin Main Net",
+		ID:   "0xd9b4bef9",
+		Port: 8333,
+		Pver: 70001,
+		TTL:  600,
+		Seeders: []string{
+			"seed.bitcoin.sipa.be",
+			"dnsseed.bluematt.me",
+			"dnsseed.bitcoin.dashjr-list-of-p2p-nodes.us",
+			"seed.bitcoinstats.com",
+			"seed.bitcoin.jonasschnelli.ch",
+			"seed.btc.petertodd.net",
+			"seed.bitcoin.sprovoost.nl",
+			"dnsseed.emzy.de",
+			"seed.bitcoin.wiz.biz",
+			"seed.mainnet.achownodes.xyz",
+		},
+	},
+	"bitcoin-testnet3": {
+		Name: "BitcoinNet-Test",
+		Desc: "Bitcoin Test Net 3",
+		ID:   "0x0709110b",
+		Port: 18333,
+		Pver: 70001,
+		TTL:  300,
+		Seeders: []string{
+			"testnet-seed.bitcoin.jonasschnelli.ch",
+			"seed.tbtc.petertodd.net",
+			"seed.testnet.bitcoin.sprovoost.nl",
+			"testnet-seed.bluematt.me",
+			"seed.testnet.achownodes.xyz",
+		},
+	},
+	"bitcoin-testnet4": {
+		Name: "BitcoinNet-Test4",
+		Desc: "Bitcoin Test Net 4",
+		ID:   "0x283f161c",
+		Port: 48333,
+		Pver: 70001,
+		TTL:  300,
+		Seeders: []string{
+			"seed.testnet4.bitcoin.sprovoost.nl",
+			"seed.testnet4.wiz.biz",
+		},
+	},
+	"bitcoin-signet": {
+		Name:            "BitcoinNet-Signet",
+		Desc:            "Bitcoin Signet",
+		Port:            38333,
+		Pver:            70001,
+		TTL:             300,
+		SignetChallenge: defSignetChallenge,
+		Seeders: []string{
+			"seed.signet.bitcoin.sprovoost.nl",
+			"seed.signet.achownodes.xyz",
+		},
+	},
+	"namecoin-main": {
+		Name: "NamecoinNet",
+		Desc: "Namecoin Main Net",
+		ID:   "0xfeb4bef9",
+		Port: 8334,
+		Pver: 70001,
+		TTL:  600,
+		Seeders: []string{
+			"nmc.seed.quisquis.de",
+			"seed.nmc.markasoftware.com",
+			"seed.namecoin.libreisp.se",
+			"dnsseed1.nmc.dotbit.zone",
+			"dnsseed2.nmc.dotbit.zone",
+			"dnsseed.nmc.testls.space",
+		},
+	},
+	"twister": {
+		Name: "TwisterNet",
+		Desc: "Peer to Peer Microblogging Network",
+		ID:   "0xd2bbdaf0",
+		Port: 28333,
+		Pver: 60000,
+		TTL:  600,
+		Seeders: []string{
+			"seed2.twister.net.co",
+			"seed.twister.net.co",
+			"seed3.twister.net.co",
+		},
+	},
+	"pkt": {
+		Name: "PKT",
+		Desc: "pkt.cash mainnet",
+		ID:   "0x082f00fc",
+		Port: 64764,
+		Pver: 70001,
+		TTL:  600,
+		Seeders: []string{
+			"seed.cjd.li",
+			"pktdseed.pkt.world",
+		},
+	},
+}
+
+// presetNames returns the sorted names of the known networks
+func presetNames() []string {
+	var names []string
+	for n := range presets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// presetNetwork returns a copy of a known network that is safe to change
+func presetNetwork(name string) (JNetwork, error) {
+	p, ok := presets[name]
+	if ok == false {
+		return JNetwork{}, fmt.Errorf("Unknown Preset %s. Must be one of %v", name, presetNames())
+	}
+	p.Seeders = append([]string(nil), p.Seeders...)
+	p.InitialIPs = append([]string(nil), p.InitialIPs...)
+	p.Checkpoints = append([]JCheckpoint(nil), p.Checkpoints...)
+	return p, nil
+}
+
+// decodeNetwork decodes a network file after converting any old field names. Fields
+// that are not in JNetwork are an error. If the file names a preset then the file is
+// decoded over the preset so only the fields in the file are changed. A file with its
+// own SignetChallenge does not get the preset Seeders. It returns a note for each old
+// field that was converted
+func decodeNetwork(data []byte) (JNetwork, []string, error) {
+
+	var jnw JNetwork
+	fields, notes, err := migrateNetwork(data)
+	if err != nil {
+		return jnw, nil, err
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return jnw, nil, err
+	}
+	if err = strictDecode(data, &jnw); err != nil {
+		return jnw, nil, err
+	}
+	if jnw.Preset == "" {
+		return jnw, notes, nil
+	}
+
+	p, err := presetNetwork(jnw.Preset)
+	if err != nil {
+		return jnw, nil, err
+	}
+	challenge := p.SignetChallenge
+	if err = strictDecode(data, &p); err != nil {
+		return jnw, nil, err
+	}
+
+	// the preset seeders only know the nodes of the preset signet so a custom
+	// signet must list its own
+	if p.SignetChallenge != challenge && jnw.Seeders == nil {
+		p.Seeders = nil
+	}
+	return p, notes, nil
+}
+
+// signetMagic returns the network magic for a signet challenge script. It is the
+// first 4 bytes of the double sha256 of the challenge serialized with its length
+func signetMagic(challenge string) (wire.BitcoinNet, error) {
+
+	script, err := hex.DecodeString(challenge)
+	if err != nil || len(script) == 0 {
+		return 0, fmt.Errorf("Invalid SignetChallenge %s", challenge)
+	}
+	var b bytes.Buffer
+	wire.WriteVarBytes(&b, 0, script)
+	h := chainhash.DoubleHashB(b.Bytes())
+	return wire.BitcoinNet(binary.LittleEndian.Uint32(h[:4])), nil
+}
+
+// networkMagic returns the magic from the ID or from the signet challenge. If both
+// are set then they must agree
+func networkMagic(jnw JNetwork) (wire.BitcoinNet, error) {
+
+	var id wire.BitcoinNet
+	if jnw.ID != "" {
+		t1, err := strconv.ParseUint(jnw.ID, 0, 32)
+		if err != nil {
+			return 0, fmt.Errorf("Error converting Network Magic number: %v", err)
+		}
+		id = wire.BitcoinNet(t1)
+	}
+	if jnw.SignetChallenge == "" {
+		if jnw.ID == "" {
+			return 0, fmt.Errorf("No Network Magic number (ID) supplied")
+		}
+		return id, nil
+	}
+
+	sid, err := signetMagic(jnw.SignetChallenge)
+	if err != nil {
+		return 0, err
+	}
+	if jnw.ID != "" && id != sid {
+		return 0, fmt.Errorf("ID %s does not match the SignetChallenge magic 0x%08x", jnw.ID, uint32(sid))
+	}
+	return sid, nil
+}
+
+// writePreset writes the network file for a known network so it can be edited
+// and loaded with -netfile
+func writePreset(name string) (string, error) {
+
+	jnw, err := presetNetwork(name)
+	if err != nil {
+		return "", err
+	}
+	if jnw.SignetChallenge != "" {
+		id, err := signetMagic(jnw.SignetChallenge)
+		if err != nil {
+			return "", err
+		}
+		jnw.ID = fmt.Sprintf("0x%08x", uint32(id))
+	}
+	jnw.DNSName = newDNSNames("seeder.example.com")
+
+	j, err := json.MarshalIndent(jnw, "", " ")
+	if err != nil {
+		return "", fmt.Errorf("Error encoding preset %s - %v", name, err)
+	}
+	fName := name + ".json"
+	if err = ioutil.WriteFile(fName, append(j, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("Error writing %s - %v", fName, err)
+	}
+	return fName, nil
+}
+
+/*
+
+ */
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestSignetMagic(t *testing.T) {
+
+	id, err := signetMagic(defSignetChallenge)
+	if err != nil || id != 0x40cf030a {
+		t.Errorf("signet magic: 0x%08x %v expected: 0x40cf030a", uint32(id), err)
+	}
+
+	var tests = []struct {
+		id        string
+		challenge string
+		magic     wire.BitcoinNet
+		ok        bool
+	}{
+		{"0xd9b4bef9", "", wire.MainNet, true},
+		{"", defSignetChallenge, 0x40cf030a, true},
+		{"0x40cf030a", defSignetChallenge, 0x40cf030a, true},
+		{"0xd9b4bef9", defSignetChallenge, 0, false},
+		{"", "51", 0, true},
+		{"", "xyz", 0, false},
+		{"", "", 0, false},
+	}
+	for _, tt := range tests {
+		magic, err := networkMagic(JNetwork{ID: tt.id, SignetChallenge: tt.challenge})
+		if (err == nil) != tt.ok {
+			t.Errorf("id: %q challenge: %q error: %v", tt.id, tt.challenge, err)
+			continue
+		}
+		if tt.magic != 0 && magic != tt.magic {
+			t.Errorf("id: %q challenge: %q magic: 0x%08x expected: 0x%08x", tt.id, tt.challenge, uint32(magic), uint32(tt.magic))
+		}
+	}
+}
+
+func TestPresets(t *testing.T) {
+
+	// every preset loads once the operator sets a DNSName
+	for _, name := range presetNames() {
+		jnw, err := presetNetwork(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jnw.DNSName = newDNSNames("seed.example.com")
+		s, err := initNetwork(jnw)
+		if err != nil {
+			t.Errorf("preset %s: %v", name, err)
+			continue
+		}
+		if len(s.seeders) == 0 {
+			t.Errorf("preset %s has no seeders", name)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fName := filepath.Join(dir, "main.json")
+	j := `{"Preset": "bitcoin-main", "DNSName": "seed.example.com", "Port": 8444, "Seeders": ["seed.example.net"]}`
+	if err = ioutil.WriteFile(fName, []byte(j), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := loadNetwork(fName)
+	if err != nil {
+		t.Fatalf("unable to load preset file: %v", err)
+	}
+	if s.id != wire.MainNet || s.name != "BitcoinNet" || s.port != 8444 || s.dnsHost != "seed.example.com" ||
+		len(s.seeders) != 1 || s.seeders[0] != "seed.example.net" {
+		t.Errorf("preset file loaded as id: %v name: %s port: %v dns: %s seeders: %v", s.id, s.name, s.port, s.dnsHost, s.seeders)
+	}
+
+	// the file must not change the preset for the next network that uses it
+	if p := presets["bitcoin-main"]; p.Port != 8333 || len(p.Seeders) == 1 {
+		t.Errorf("preset changed by a network file: %+v", p)
+	}
+
+	// a custom signet only needs its own challenge to get the right magic
+	j = `{"Preset": "bitcoin-signet", "DNSName": "seed.example.com", "SignetChallenge": "51"}`
+	if err = ioutil.WriteFile(fName, []byte(j), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = loadNetwork(fName); err != nil {
+		t.Fatalf("unable to load signet file: %v", err)
+	}
+	if magic, _ := signetMagic("51"); s.id != magic || s.id == 0x40cf030a {
+		t.Errorf("custom signet magic: 0x%08x", uint32(s.id))
+	}
+	// and does not get the seeders of the default signet
+	if len(s.seeders) != 0 {
+		t.Errorf("custom signet has the default signet seeders: %v", s.seeders)
+	}
+	jnw, _, err := decodeNetwork([]byte(`{"Preset": "bitcoin-signet", "SignetChallenge": "51", "Seeders": ["seed.example.net"]}`))
+	if err != nil || len(jnw.Seeders) != 1 || jnw.Seeders[0] != "seed.example.net" {
+		t.Errorf("custom signet seeders: %v %v", jnw.Seeders, err)
+	}
+
+	if _, _, err = decodeNetwork([]byte(`{"Preset": "dogecoin"}`)); err == nil {
+		t.Errorf("unknown preset accepted")
+	}
+}
+
+/*
+
+ */
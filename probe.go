@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+const defProbeTimeout = 30 // default seconds a node has to return a probed block
+
+const (
+	// block probe status of a node
+	probeUnknown = iota // not probed yet or does not claim to be a full node
+	probeOK             // returned the historical block we asked for
+	probeFail           // claims to be a full node but did not return the block
+)
+
+// needProbe returns true if the node is due to have its block availability probed
+func (s *dnsseeder) needProbe(nd *node) bool {
+	if s.probeInterval <= 0 || len(s.checkpoints) == 0 {
+		return false
+	}
+	return time.Since(nd.probed) > time.Hour*time.Duration(s.probeInterval)
+}
+
+// probeBlocks checks a node that claims SFNodeNetwork can serve historical blocks.
+// It asks for the headers after a random checkpoint and then for the block at a random
+// height in those headers. Both must arrive within the probe deadline.
+func (s *dnsseeder) probeBlocks(conn net.Conn, r *result) (uint32, error) {
+
+	conn.SetDeadline(time.Now().Add(time.Second * time.Duration(s.probeTimeout)))
+
+	cp := s.checkpoints[rand.Intn(len(s.checkpoints))]
+	headers, err := s.getHeaders(conn, r, &cp.hash)
+	if err != nil {
+		return probeFail, err
+	}
+	if len(headers) == 0 || headers[0].PrevBlock != cp.hash {
+		return probeFail, fmt.Errorf("no headers after checkpoint %v", cp.height)
+	}
+
+	i := rand.Intn(len(headers))
+	hash := headers[i].BlockHash()
+
+	msgGetData := wire.NewMsgGetData()
+	msgGetData.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &hash))
+	if err = s.codec.WriteMessage(conn, msgGetData, s.pver, s.id); err != nil {
+		return probeFail, err
+	}
+
+	// ignore any other messages the node sends before the block
+	for c := 0; c < 25; c++ {
+		msg, _, err := s.codec.ReadMessage(conn, s.pver, s.id)
+		if err != nil {
+			if _, ok := err.(*wire.MessageError); ok {
+				continue
+			}
+			return probeFail, err
+		}
+		switch msg := msg.(type) {
+		case *wire.MsgBlock:
+			if msg.Header.BlockHash() != hash {
+				return probeFail, fmt.Errorf("received the wrong block for height %v", cp.height+int32(i)+1)
+			}
+			return probeOK, nil
+		case *wire.MsgNotFound:
+			return probeFail, fmt.Errorf("block at height %v not found", cp.height+int32(i)+1)
+		default:
+			if config.debug {
+				log.Printf("%s - debug - %s - ignoring message waiting for block - %v\n", s.name, r.node, msg.Command())
+			}
+		}
+	}
+	return probeFail, errors.New("did not receive block in first 25 messages")
+}
+
+// probe2str will return the string description of the block probe status
+func probe2str(status uint32) string {
+	switch status {
+	case probeUnknown:
+		return "not probed"
+	case probeOK:
+		return "ok"
+	case probeFail:
+		return "failed"
+	default:
+		return "Unknown"
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+)
+
+// netSpec is a network file or a network written inline in the server config file
+type netSpec struct {
+	name string // file name or where the inline network came from
+	data []byte // json of an inline network or nil to read the file
+}
+
+// networkLoader returns the seeders by name and the order they were loaded in
+type networkLoader func() (map[string]*dnsseeder, []string, error)
+
+// loadNetworks loads and validates every network file. It returns the new seeders
+// by name and the order the files were loaded in
+func loadNetworks(files []string) (map[string]*dnsseeder, []string, error) {
+
+	specs := make([]netSpec, len(files))
+	for i, nwFile := range files {
+		specs[i] = netSpec{name: nwFile}
+	}
+	return loadNetworkSpecs(specs)
+}
+
+// loadNetworkSpecs loads and validates every network file or inline network
+func loadNetworkSpecs(specs []netSpec) (map[string]*dnsseeder, []string, error) {
+
+	seeders := make(map[string]*dnsseeder)
+	order := []string{}
+
+	for _, ns := range specs {
+		var nnw *dnsseeder
+		var err error
+		if ns.data == nil {
+			nnw, err = loadNetwork(ns.name)
+		} else {
+			nnw, err = loadNetworkData(ns.name, ns.data)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error loading data from netfile %s - %v", ns.name, err)
+		}
+		if nnw == nil {
+			continue
+		}
+		if dup, err := isDuplicateSeeder(nnw, seeders); dup == true {
+			return nil, nil, fmt.Errorf("Error loading data from netfile %s - %v", ns.name, err)
+		}
+		seeders[nnw.name] = nnw
+		order = append(order, nnw.name)
+	}
+	return seeders, order, nil
+}
+
+// reloadFrom loads the networks and applies the differences to the running seeders.
+// New seeders are started, removed seeders are stopped and changed seeders are updated
+// in place so they keep their node list. If any network has errors the running config
+// is not changed
+func reloadFrom(load networkLoader, done <-chan struct{}, wg *sync.WaitGroup) {
+
+	next, order, err := load()
+	if err != nil {
+		log.Printf("status - network reload failed. Keeping the running config - %v\n", err)
+		return
+	}
+
+	// updated seeders serve their new dns settings at once rather than at the next
+	// dns update. updateDNS takes config.smtx so this runs after it is unlocked
+	var refresh []*dnsseeder
+	defer func() {
+		for _, s := range refresh {
+			updateDNS(s)
+		}
+	}()
+
+	config.smtx.Lock()
+	defer config.smtx.Unlock()
+
+	// stop the seeders that are no longer configured
+	for name, s := range config.seeders {
+		if _, ok := next[name]; ok == false {
+			s.stopSeeder()
+			delete(config.seeders, name)
+			log.Printf("status - network reload stopped network: %s\n", name)
+		}
+	}
+
+	for _, name := range order {
+		ns := next[name]
+		s, ok := config.seeders[name]
+
+		switch {
+		case ok == false:
+			config.seeders[name] = ns
+			wg.Add(1)
+			go ns.runSeeder(done, wg)
+			log.Printf("status - network reload started network: %s\n", name)
+
+		case s.id != ns.id || s.port != ns.port || s.pver != ns.pver || s.codec.String() != ns.codec.String():
+			// a different network or protocol so the node list is no use
+			s.stopSeeder()
+			config.seeders[name] = ns
+			wg.Add(1)
+			go func(old *dnsseeder) {
+				// both seeders use the same snapshot file so wait for the old seeder to
+				// save its final snapshot before the new seeder loads it
+				<-old.stopped
+				ns.runSeeder(done, wg)
+			}(s)
+			log.Printf("status - network reload restarted network: %s with a new ID, Port, Pver or Codec\n", name)
+
+		default:
+			if changed := s.update(ns); len(changed) > 0 {
+				refresh = append(refresh, s)
+				log.Printf("status - network reload updated network: %s changed: %v\n", name, changed)
+			}
+		}
+	}
+	config.order = order
+}
+
+// update copies the config from a newly loaded seeder for the same network into the
+// running seeder and returns the names of the settings that changed
+func (s *dnsseeder) update(ns *dnsseeder) []string {
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	changed := []string{}
+	set := func(name string, differ bool) bool {
+		if differ {
+			changed = append(changed, name)
+		}
+		return differ
+	}
+
+	// names that are kept keep their records so only the names that changed are touched
+	removed, edited := diffDNSNames(s.dnsNames, ns.dnsNames)
+	if set("DNSName", len(removed) > 0 || len(edited) > 0) {
+		for _, host := range removed {
+			removeDNS(host)
+		}
+		for _, n := range edited {
+			if n.soa == nil {
+				removeSOA(n.host)
+			}
+		}
+	}
+	s.dnsHost = ns.dnsHost
+	s.dnsNames = ns.dnsNames
+
+	if set("Desc", s.desc != ns.desc) {
+		s.desc = ns.desc
+	}
+	if set("TTL", s.ttl != ns.ttl) {
+		s.ttl = ns.ttl
+	}
+	if set("InitialIPs", reflect.DeepEqual(s.initialIPs, ns.initialIPs) == false) {
+		s.initialIPs = ns.initialIPs
+	}
+	if set("Seeders", reflect.DeepEqual(s.seeders, ns.seeders) == false) {
+		s.seeders = ns.seeders
+	}
+	if set("RPC", s.rpc.equal(ns.rpc) == false) {
+		s.rpc = ns.rpc
+	}
+	if set("MaxCrawls", s.maxCrawls != ns.maxCrawls) {
+		s.maxCrawls = ns.maxCrawls
+	}
+	if set("BindIP", s.bindIPv4.Equal(ns.bindIPv4) == false || s.bindIPv6.Equal(ns.bindIPv6) == false) {
+		s.bindIPv4 = ns.bindIPv4
+		s.bindIPv6 = ns.bindIPv6
+		s.dialer = ns.dialer
+	}
+	if set("DisableIPv4", s.disableIPv4 != ns.disableIPv4) {
+		s.disableIPv4 = ns.disableIPv4
+	}
+	if set("DisableIPv6", s.disableIPv6 != ns.disableIPv6) {
+		s.disableIPv6 = ns.disableIPv6
+	}
+	if set("AllowPrivate", s.allowPrivate != ns.allowPrivate) {
+		s.allowPrivate = ns.allowPrivate
+	}
+	if set("Checkpoints", reflect.DeepEqual(s.checkpoints, ns.checkpoints) == false ||
+		reflect.DeepEqual(s.minChainWork, ns.minChainWork) == false) {
+		s.checkpoints = ns.checkpoints
+		s.minChainWork = ns.minChainWork
+	}
+	if set("Probe", s.probeInterval != ns.probeInterval || s.probeTimeout != ns.probeTimeout) {
+		s.probeInterval = ns.probeInterval
+		s.probeTimeout = ns.probeTimeout
+	}
+	if set("MaxPerGroup", s.maxPerGroup != ns.maxPerGroup || s.maxDNSPerGrp != ns.maxDNSPerGrp) {
+		s.maxPerGroup = ns.maxPerGroup
+		s.maxDNSPerGrp = ns.maxDNSPerGrp
+	}
+	if set("Policy", s.policy2str() != ns.policy2str()) {
+		s.maxStart = ns.maxStart
+		s.delay = ns.delay
+		s.backoff = ns.backoff
+		s.maxSize = ns.maxSize
+		s.crawlDelay = ns.crawlDelay
+		s.auditDelay = ns.auditDelay
+		s.dnsDelay = ns.dnsDelay
+		s.maxFails = ns.maxFails
+		s.maxTo = ns.maxTo
+		s.evictPolicy = ns.evictPolicy
+		s.minGood = ns.minGood
+
+		// runSeeder needs to restart its tickers with the new delays
+		select {
+		case s.updated <- struct{}{}:
+		default:
+		}
+	}
+	return changed
+}
+
+// stopSeeder tells the seeder goroutine to shutdown and removes its dns records.
+// The caller must hold config.smtx
+func (s *dnsseeder) stopSeeder() {
+	close(s.stop)
+	s.mtx.RLock()
+	for _, n := range s.dnsNames {
+		removeDNS(n.host)
+	}
+	s.mtx.RUnlock()
+}
+
+// removeDNS removes the dns records we serve for a dns name
+func removeDNS(dnsHost string) {
+	config.dnsmtx.Lock()
+	delete(config.dns, dnsHost+".A")
+	delete(config.dns, "nonstd."+dnsHost+".A")
+	delete(config.dns, dnsHost+".AAAA")
+	delete(config.dns, "nonstd."+dnsHost+".AAAA")
+	delete(config.dns, dnsHost+".SOA")
+	config.dnsmtx.Unlock()
+}
+
+// removeSOA removes the SOA record of a dns name that no longer has one
+func removeSOA(dnsHost string) {
+	config.dnsmtx.Lock()
+	delete(config.dns, dnsHost+".SOA")
+	config.dnsmtx.Unlock()
+}
+
+// isStopped returns true if the seeder has been stopped by a network reload
+func (s *dnsseeder) isStopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// drainCrawls processes the results of the crawls still running for a stopped
+// seeder so their crawl slots are released
+func (s *dnsseeder) drainCrawls(rc chan *result) {
+	for {
+		s.mtx.RLock()
+		n := s.activeCrawls
+		s.mtx.RUnlock()
+		if n <= 0 {
+			return
+		}
+		s.processResult(<-rc)
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gombadi/dnsseeder/simpeer"
+	"github.com/miekg/dns"
+)
+
+// fileLoader returns the loader main uses for a list of -netfile files
+func fileLoader(files ...string) networkLoader {
+	return func() (map[string]*dnsseeder, []string, error) { return loadNetworks(files) }
+}
+
+func TestReloadNetworks(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeNet := func(fName string, jnw JNetwork) string {
+		j, err := json.Marshal(jnw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fName = filepath.Join(dir, fName)
+		if err = ioutil.WriteFile(fName, j, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return fName
+	}
+
+	netA := simNetwork()
+	netA.Name = "NetA"
+	netB := simNetwork()
+	netB.Name = "NetB"
+	netB.ID = "0x0709110c"
+	netB.DNSName = newDNSNames("seed.b.test")
+
+	fA := writeNet("a.json", netA)
+	fB := writeNet("b.json", netB)
+
+	config.dns = make(map[string][]dns.RR)
+	if config.seeders, config.order, err = loadNetworks([]string{fA, fB}); err != nil {
+		t.Fatalf("unable to load networks: %v", err)
+	}
+	defer func() { config.seeders, config.order = nil, nil }()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for _, s := range config.seeders {
+		wg.Add(1)
+		go s.runSeeder(done, &wg)
+	}
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	sA, sB := config.seeders["NetA"], config.seeders["NetB"]
+	config.dns["seed.b.test.A"] = []dns.RR{newRR("seed.b.test", []byte{1, 2, 3, 4}, 60, false)}
+
+	// a network file with errors must not change the running config
+	netBad := simNetwork()
+	netBad.Name = "NetBad"
+	netBad.ID = "0x0709110c"
+	fBad := writeNet("bad.json", netBad)
+	reloadFrom(fileLoader(fA, fB, fBad), done, &wg)
+	if len(config.seeders) != 2 || config.seeders["NetBad"] != nil {
+		t.Errorf("reload with a duplicate magic id changed the running seeders")
+	}
+
+	// change NetA, remove NetB and add NetC
+	netA.TTL = 300
+	netA.DNSName = newDNSNames("seed.a.test")
+	netA.MaxFails = 20
+	writeNet("a.json", netA)
+	netC := simNetwork()
+	netC.Name = "NetC"
+	netC.ID = "0x0709110d"
+	netC.DNSName = newDNSNames("seed.c.test")
+	fC := writeNet("c.json", netC)
+
+	reloadFrom(fileLoader(fA, fC), done, &wg)
+
+	config.smtx.RLock()
+	defer config.smtx.RUnlock()
+
+	if config.seeders["NetA"] != sA {
+		t.Errorf("NetA was replaced instead of updated in place")
+	}
+	sA.mtx.RLock()
+	if sA.ttl != 300 || sA.dnsHost != "seed.a.test" || sA.maxFails != 20 {
+		t.Errorf("NetA not updated. ttl: %v dns: %s maxFails: %v", sA.ttl, sA.dnsHost, sA.maxFails)
+	}
+	sA.mtx.RUnlock()
+
+	if config.seeders["NetB"] != nil || sB.isStopped() == false {
+		t.Errorf("NetB was not stopped")
+	}
+	config.dnsmtx.RLock()
+	if _, ok := config.dns["seed.b.test.A"]; ok {
+		t.Errorf("dns records for removed network still served")
+	}
+	config.dnsmtx.RUnlock()
+
+	if config.seeders["NetC"] == nil {
+		t.Errorf("NetC was not started")
+	}
+	if len(config.order) != 2 || config.order[0] != "NetA" || config.order[1] != "NetC" {
+		t.Errorf("network order: %v expected: [NetA NetC]", config.order)
+	}
+}
+
+func TestReloadRestart(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(d string) { config.datadir = d }(config.datadir)
+	config.datadir = dir
+
+	// every crawl fails at once so the nodes stay in theList
+	sn := simpeer.NewNetwork()
+	jnw := simNetwork()
+	load := func() (map[string]*dnsseeder, []string, error) {
+		s, err := initNetwork(jnw)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.dialer, s.resolver = sn, sn
+		return map[string]*dnsseeder{s.name: s}, []string{s.name}, nil
+	}
+
+	config.dns = make(map[string][]dns.RR)
+	seeders, order, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.smtx.Lock()
+	config.seeders, config.order = seeders, order
+	config.smtx.Unlock()
+	defer func() {
+		config.smtx.Lock()
+		config.seeders, config.order = nil, nil
+		config.smtx.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	old := seeders[jnw.Name]
+	wg.Add(1)
+	go old.runSeeder(done, &wg)
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	old.mtx.Lock()
+	for i := 1; i <= 3; i++ {
+		old.addNode(simAddr(fmt.Sprintf("20.%d.0.1", i), 8333), "")
+	}
+	old.mtx.Unlock()
+
+	// a new port restarts the network and the new seeder always starts from the
+	// snapshot the old seeder saves when it stops
+	jnw.Port = 8444
+	reloadFrom(load, done, &wg)
+
+	config.smtx.RLock()
+	ns := config.seeders[jnw.Name]
+	config.smtx.RUnlock()
+	if ns == old || ns.port != 8444 {
+		t.Fatalf("network not restarted with the new port")
+	}
+	<-old.stopped
+	for i := 0; i < 100; i++ {
+		ns.mtx.RLock()
+		n := len(ns.theList)
+		ns.mtx.RUnlock()
+		if n == 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ns.mtx.RLock()
+	if len(ns.theList) != 3 {
+		t.Errorf("restarted network has %v nodes expected: 3 from the old snapshot", len(ns.theList))
+	}
+	ns.mtx.RUnlock()
+}
+
+func TestReloadKeepsDNS(t *testing.T) {
+
+	jnw := simNetwork()
+	jnw.DNSName = newDNSNames("seed.a.test", "seed.b.test")
+	load := func() (map[string]*dnsseeder, []string, error) {
+		s, err := initNetwork(jnw)
+		if err != nil {
+			return nil, nil, err
+		}
+		return map[string]*dnsseeder{s.name: s}, []string{s.name}, nil
+	}
+
+	config.dns = make(map[string][]dns.RR)
+	seeders, order, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := seeders[jnw.Name]
+	for i := 1; i <= 3; i++ {
+		nd := s.addNode(simAddr(fmt.Sprintf("20.%d.0.1", i), 8333), "")
+		nd.status = statusCG
+		nd.lastConnect = time.Now()
+	}
+	config.smtx.Lock()
+	config.seeders, config.order = seeders, order
+	config.smtx.Unlock()
+	defer func() {
+		config.smtx.Lock()
+		config.seeders, config.order = nil, nil
+		config.smtx.Unlock()
+	}()
+	updateDNS(s)
+
+	// adding a name and changing the ttl of another must not blank the names
+	// that are kept until the next dns update
+	jnw.DNSName = JDNSNames{{Name: "seed.a.test"}, {Name: "seed.b.test", TTL: 300}, {Name: "seed.c.test"}}
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	reloadFrom(load, done, &wg)
+
+	config.smtx.RLock()
+	if config.seeders[jnw.Name] != s {
+		t.Errorf("network was replaced instead of updated in place")
+	}
+	config.smtx.RUnlock()
+
+	config.dnsmtx.RLock()
+	defer config.dnsmtx.RUnlock()
+	for _, name := range []string{"seed.a.test", "seed.b.test", "seed.c.test"} {
+		if rrs := config.dns[name+".A"]; len(rrs) != 3 {
+			t.Errorf("records for %s after the reload: %v expected: 3", name, len(rrs))
+		}
+	}
+	if rrs := config.dns["seed.b.test.A"]; len(rrs) > 0 && rrs[0].Header().Ttl != 300 {
+		t.Errorf("seed.b.test ttl after the reload: %v expected: 300", rrs[0].Header().Ttl)
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// routability of an address. Anything other than routeOK is the reason it was rejected
+	routeOK            = iota // public unicast address
+	routeUnspecified          // 0.0.0.0 or ::
+	routeLoopback             // 127.0.0.0/8 or ::1
+	routePrivate              // RFC1918 or IPv6 unique local fc00::/7
+	routeLinkLocal            // 169.254.0.0/16 or fe80::/10
+	routeMulticast            // 224.0.0.0/4 or ff00::/8
+	routeDocumentation        // RFC5737 or 2001:db8::/32
+	routeCGNAT                // RFC6598 shared address space 100.64.0.0/10
+	routeReserved             // other reserved ranges and IPv6 outside 2000::/3
+	maxRouteReasons           // used to allocate arrays
+)
+
+// routeRanges lists the ranges that are not publicly routable. More specific ranges
+// must come before the ranges that contain them
+var routeRanges = []struct {
+	cidr   string
+	reason uint32
+}{
+	{"0.0.0.0/8", routeReserved},
+	{"10.0.0.0/8", routePrivate},
+	{"100.64.0.0/10", routeCGNAT},
+	{"127.0.0.0/8", routeLoopback},
+	{"169.254.0.0/16", routeLinkLocal},
+	{"172.16.0.0/12", routePrivate},
+	{"192.0.0.0/24", routeReserved},
+	{"192.0.2.0/24", routeDocumentation},
+	{"192.168.0.0/16", routePrivate},
+	{"198.18.0.0/15", routeReserved},
+	{"198.51.100.0/24", routeDocumentation},
+	{"203.0.113.0/24", routeDocumentation},
+	{"224.0.0.0/4", routeMulticast},
+	{"240.0.0.0/4", routeReserved},
+	{"::1/128", routeLoopback},
+	{"100::/64", routeReserved},
+	{"2001:10::/28", routeReserved},
+	{"2001:db8::/32", routeDocumentation},
+	{"fc00::/7", routePrivate},
+	{"fe80::/10", routeLinkLocal},
+	{"fec0::/10", routeReserved},
+	{"ff00::/8", routeMulticast},
+}
+
+var routeNets []*net.IPNet
+var globalUnicast6 *net.IPNet
+
+func init() {
+	for _, r := range routeRanges {
+		_, n, err := net.ParseCIDR(r.cidr)
+		if err != nil {
+			panic(fmt.Sprintf("invalid route range %s: %v", r.cidr, err))
+		}
+		routeNets = append(routeNets, n)
+	}
+	_, globalUnicast6, _ = net.ParseCIDR("2000::/3")
+}
+
+// classifyIP returns routeOK if the address is publicly routable or the reason it is not
+func classifyIP(ip net.IP) uint32 {
+
+	if ip == nil || ip.IsUnspecified() {
+		return routeUnspecified
+	}
+
+	// ipv4 mapped addresses are checked as ipv4
+	if x := ip.To4(); x != nil {
+		ip = x
+		if ip.Equal(net.IPv4bcast) {
+			return routeReserved
+		}
+	}
+
+	for i, n := range routeNets {
+		if n.Contains(ip) {
+			return routeRanges[i].reason
+		}
+	}
+
+	if len(ip) == net.IPv6len && globalUnicast6.Contains(ip) == false {
+		return routeReserved
+	}
+	return routeOK
+}
+
+// routable returns routeOK if this seeder can use the address or the reason it can not.
+// Networks that allow private addresses can use loopback, private, link local and
+// CGNAT addresses for test networks
+func (s *dnsseeder) routable(ip net.IP) uint32 {
+	r := classifyIP(ip)
+	if s.allowPrivate {
+		switch r {
+		case routeLoopback, routePrivate, routeLinkLocal, routeCGNAT:
+			return routeOK
+		}
+	}
+	return r
+}
+
+// countReject records an address rejected for the routability reason
+func (s *dnsseeder) countReject(reason uint32) {
+	s.counts.mtx.Lock()
+	s.counts.Rejects[reason]++
+	s.counts.mtx.Unlock()
+}
+
+// rejects2str returns the reject counters for display. Reasons with no rejects are skipped
+func (s *dnsseeder) rejects2str() string {
+	var r []string
+	s.counts.mtx.RLock()
+	for reason := routeUnspecified; reason < maxRouteReasons; reason++ {
+		if c := s.counts.Rejects[reason]; c > 0 {
+			r = append(r, fmt.Sprintf("%s: %v", route2str(uint32(reason)), c))
+		}
+	}
+	s.counts.mtx.RUnlock()
+	if len(r) == 0 {
+		return "none"
+	}
+	return strings.Join(r, " ")
+}
+
+// route2str will return the string description of the routability reason
+func route2str(reason uint32) string {
+	switch reason {
+	case routeOK:
+		return "routable"
+	case routeUnspecified:
+		return "unspecified"
+	case routeLoopback:
+		return "loopback"
+	case routePrivate:
+		return "private"
+	case routeLinkLocal:
+		return "link local"
+	case routeMulticast:
+		return "multicast"
+	case routeDocumentation:
+		return "documentation"
+	case routeCGNAT:
+		return "CGNAT"
+	case routeReserved:
+		return "reserved"
+	default:
+		return "Unknown"
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestClassifyIP(t *testing.T) {
+
+	var tests = []struct {
+		ip     string
+		reason uint32
+	}{
+		{"1.2.3.4", routeOK},
+		{"8.8.8.8", routeOK},
+		{"0.0.0.0", routeUnspecified},
+		{"0.1.2.3", routeReserved},
+		{"10.1.2.3", routePrivate},
+		{"100.64.1.1", routeCGNAT},
+		{"127.0.0.1", routeLoopback},
+		{"169.254.1.1", routeLinkLocal},
+		{"172.16.5.4", routePrivate},
+		{"172.32.5.4", routeOK},
+		{"192.0.2.1", routeDocumentation},
+		{"192.168.1.1", routePrivate},
+		{"198.18.0.1", routeReserved},
+		{"198.51.100.7", routeDocumentation},
+		{"203.0.113.9", routeDocumentation},
+		{"224.0.0.1", routeMulticast},
+		{"250.1.1.1", routeReserved},
+		{"255.255.255.255", routeReserved},
+		{"::ffff:10.0.0.1", routePrivate},
+		{"::ffff:1.2.3.4", routeOK},
+		{"::", routeUnspecified},
+		{"::1", routeLoopback},
+		{"2a01:4f8::1", routeOK},
+		{"2001:db8::1", routeDocumentation},
+		{"2001:10::1", routeReserved},
+		{"fd00::1", routePrivate},
+		{"fe80::1", routeLinkLocal},
+		{"fec0::1", routeReserved},
+		{"ff02::1", routeMulticast},
+		{"100::1", routeReserved},
+		{"4000::1", routeReserved},
+	}
+
+	for _, tt := range tests {
+		if r := classifyIP(net.ParseIP(tt.ip)); r != tt.reason {
+			t.Errorf("ip: %s reason: %s expected: %s", tt.ip, route2str(r), route2str(tt.reason))
+		}
+	}
+}
+
+func TestRouteIntake(t *testing.T) {
+
+	s := &dnsseeder{
+		port:    8333,
+		maxSize: 1250,
+	}
+	s.theList = make(map[nodeAddr]*node)
+
+	for _, ip := range []string{"10.0.0.1", "192.168.1.1", "127.0.0.1", "224.0.0.1", "1.2.3.4"} {
+		s.addNode(wire.NewNetAddressIPPort(net.ParseIP(ip), 8333, 0), "")
+	}
+	if len(s.theList) != 1 {
+		t.Errorf("theList has %v nodes expected: 1", len(s.theList))
+	}
+	if s.counts.Rejects[routePrivate] != 2 || s.counts.Rejects[routeLoopback] != 1 || s.counts.Rejects[routeMulticast] != 1 {
+		t.Errorf("reject counts: %s", s.rejects2str())
+	}
+
+	// private networks can use private addresses but never multicast
+	s.allowPrivate = true
+	if s.addNode(wire.NewNetAddressIPPort(net.ParseIP("10.0.0.1"), 8333, 0), "") == nil {
+		t.Errorf("private address rejected when AllowPrivate is set")
+	}
+	if s.addNode(wire.NewNetAddressIPPort(net.ParseIP("224.0.0.1"), 8333, 0), "") != nil {
+		t.Errorf("multicast address accepted when AllowPrivate is set")
+	}
+}
+
+/*
+
+ */
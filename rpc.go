@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	rpcTimeout = 30     // seconds allowed for a call to the local node
+	srcRPC     = "rpc:" // prefix for the source group of addresses from the local node
+)
+
+// JRPC is the network file config for a trusted local node that can be asked for
+// addresses over JSON-RPC. Use CookieFile or User and Password to authenticate
+type JRPC struct {
+	URL        string
+	CookieFile string `json:",omitempty"`
+	User       string `json:",omitempty"`
+	Password   string `json:",omitempty"`
+}
+
+// rpcSource asks a local bitcoind for addresses using getnodeaddresses and getpeerinfo
+type rpcSource struct {
+	cfg    JRPC
+	client *http.Client
+	id     uint64 // last request id
+}
+
+// rpcNodeAddress is one entry from the getnodeaddresses result
+type rpcNodeAddress struct {
+	Time     int64  `json:"time"`
+	Services uint64 `json:"services"`
+	Address  string `json:"address"`
+	Port     uint16 `json:"port"`
+}
+
+// rpcPeerInfo is the part of a getpeerinfo entry we use
+type rpcPeerInfo struct {
+	Addr     string `json:"addr"`
+	Services string `json:"services"`
+	Inbound  bool   `json:"inbound"`
+}
+
+// newRPCSource checks the rpc config from the network file
+func newRPCSource(cfg JRPC) (*rpcSource, error) {
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Invalid RPC URL: %s", cfg.URL)
+	}
+	if cfg.CookieFile == "" && cfg.User == "" {
+		return nil, fmt.Errorf("RPC needs a CookieFile or a User and Password")
+	}
+	if cfg.CookieFile != "" && cfg.User != "" {
+		return nil, fmt.Errorf("RPC can use a CookieFile or a User and Password but not both")
+	}
+
+	return &rpcSource{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Second * rpcTimeout},
+	}, nil
+}
+
+// equal returns true if both sources use the same config
+func (r *rpcSource) equal(o *rpcSource) bool {
+	if r == nil || o == nil {
+		return r == o
+	}
+	return r.cfg == o.cfg
+}
+
+// auth returns the user and password for a call. The cookie file is read each time
+// as bitcoind writes a new one every time it starts
+func (r *rpcSource) auth() (string, string, error) {
+	if r.cfg.CookieFile == "" {
+		return r.cfg.User, r.cfg.Password, nil
+	}
+	b, err := ioutil.ReadFile(r.cfg.CookieFile)
+	if err != nil {
+		return "", "", fmt.Errorf("Error reading RPC cookie file: %v", err)
+	}
+	i := strings.Index(string(b), ":")
+	if i < 0 {
+		return "", "", fmt.Errorf("Error reading RPC cookie file: %s is not a cookie file", r.cfg.CookieFile)
+	}
+	return string(b[:i]), strings.TrimSpace(string(b[i+1:])), nil
+}
+
+// call sends a JSON-RPC request to the local node and decodes the result
+func (r *rpcSource) call(method string, params []interface{}, result interface{}) error {
+
+	user, pass, err := r.auth()
+	if err != nil {
+		return err
+	}
+
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "1.0",
+		"id":      atomic.AddUint64(&r.id, 1),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", r.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(user, pass)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("RPC %s failed: %v", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("RPC %s failed: authentication rejected", method)
+	}
+
+	// bitcoind returns errors with a 404 or 500 status and the details in the body
+	var reply struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("RPC %s failed: %s %v", method, resp.Status, err)
+	}
+	if reply.Error != nil {
+		return fmt.Errorf("RPC %s failed: %s (%v)", method, reply.Error.Message, reply.Error.Code)
+	}
+	if err = json.Unmarshal(reply.Result, result); err != nil {
+		return fmt.Errorf("RPC %s returned an invalid result: %v", method, err)
+	}
+	return nil
+}
+
+// addresses returns the addresses known to the local node and the outbound peers it is
+// connected to. Inbound peers are skipped as they connect from a temporary port
+func (r *rpcSource) addresses() ([]bootAddr, error) {
+
+	var addrs []bootAddr
+
+	// a count of 0 asks for every address the node knows about
+	var nas []rpcNodeAddress
+	if err := r.call("getnodeaddresses", []interface{}{0}, &nas); err != nil {
+		return nil, err
+	}
+	for _, na := range nas {
+		if ip := net.ParseIP(na.Address); ip != nil {
+			addrs = append(addrs, bootAddr{
+				na:  wire.NewNetAddressTimestamp(time.Unix(na.Time, 0), wire.ServiceFlag(na.Services), ip, na.Port),
+				src: srcRPC + "getnodeaddresses",
+			})
+		}
+	}
+
+	var peers []rpcPeerInfo
+	if err := r.call("getpeerinfo", nil, &peers); err != nil {
+		return nil, err
+	}
+	for _, p := range peers {
+		if p.Inbound {
+			continue
+		}
+		a, err := parseNodeAddr(p.Addr)
+		if err != nil {
+			continue
+		}
+		var services uint64
+		fmt.Sscanf(p.Services, "%x", &services)
+		addrs = append(addrs, bootAddr{
+			na:  wire.NewNetAddressTimestamp(time.Now(), wire.ServiceFlag(services), a.IP(), a.Port()),
+			src: srcRPC + "getpeerinfo",
+		})
+	}
+	return addrs, nil
+}
+
+/*
+
+ */
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gombadi/dnsseeder/simpeer"
+)
+
+// newRPCServer returns a stand-in for the bitcoind rpc server that accepts one user
+func newRPCServer(t *testing.T, user, pass string) *httptest.Server {
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); ok == false || u != user || p != pass {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			ID     uint64        `json:"id"`
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("bad rpc request: %v", err)
+			return
+		}
+
+		now := time.Now().Unix()
+		var result string
+		switch req.Method {
+		case "getnodeaddresses":
+			if len(req.Params) != 1 {
+				t.Errorf("getnodeaddresses params: %v", req.Params)
+			}
+			result = fmt.Sprintf(`[
+				{"time": %v, "services": 1033, "address": "1.2.3.4", "port": 8333, "network": "ipv4"},
+				{"time": %v, "services": 1, "address": "2a01:4f8::1", "port": 8333, "network": "ipv6"},
+				{"time": %v, "services": 1, "address": "abcdefghijklmnop.onion", "port": 8333, "network": "onion"}
+			]`, now, now-3*24*3600, now)
+		case "getpeerinfo":
+			result = `[
+				{"addr": "5.6.7.8:8333", "services": "0000000000000409", "inbound": false},
+				{"addr": "9.9.9.9:51234", "services": "0000000000000409", "inbound": true}
+			]`
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"result": null, "error": {"code": -32601, "message": "Method not found"}, "id": %v}`, req.ID)
+			return
+		}
+		fmt.Fprintf(w, `{"result": %s, "error": null, "id": %v}`, result, req.ID)
+	}))
+}
+
+func TestRPCSource(t *testing.T) {
+
+	srv := newRPCServer(t, "__cookie__", "secret")
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cookie := filepath.Join(dir, ".cookie")
+	if err = ioutil.WriteFile(cookie, []byte("__cookie__:secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range []JRPC{
+		{URL: "localhost:8332", User: "u"},
+		{URL: srv.URL},
+		{URL: srv.URL, User: "u", CookieFile: cookie},
+	} {
+		if _, err = newRPCSource(bad); err == nil {
+			t.Errorf("invalid rpc config accepted: %+v", bad)
+		}
+	}
+
+	rpc, err := newRPCSource(JRPC{URL: srv.URL, CookieFile: cookie})
+	if err != nil {
+		t.Fatalf("unable to create rpc source: %v", err)
+	}
+	addrs, err := rpc.addresses()
+	if err != nil {
+		t.Fatalf("unable to get addresses: %v", err)
+	}
+	if len(addrs) != 3 {
+		t.Fatalf("rpc returned %v addresses expected: 3", len(addrs))
+	}
+	if addrs[2].na.IP.String() != "5.6.7.8" || addrs[2].src != "rpc:getpeerinfo" || addrs[2].na.Services != 0x409 {
+		t.Errorf("peer address: %s source: %s services: %v", addrs[2].na.IP, addrs[2].src, addrs[2].na.Services)
+	}
+
+	// unknown methods and bad passwords return errors
+	var res interface{}
+	if err = rpc.call("getnothing", nil, &res); err == nil {
+		t.Errorf("unknown rpc method did not fail")
+	}
+	bad, _ := newRPCSource(JRPC{URL: srv.URL, User: "__cookie__", Password: "wrong"})
+	if _, err = bad.addresses(); err == nil {
+		t.Errorf("bad password did not fail")
+	}
+
+	// the local node is used with the other seeders when bootstrapping. It is trusted so
+	// an address it last saw days ago is still added
+	s := newSimSeeder(t, simpeer.NewNetwork(), simNetwork())
+	s.rpc = rpc
+	if added := s.addBootstrap(s.lookupSeeders("test", s.seeders, s.rpc)); added != 3 {
+		t.Errorf("bootstrap added %v nodes from the local node expected: 3", added)
+	}
+}
+
+/*
+
+ */
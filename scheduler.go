@@ -0,0 +1,152 @@
+package main
+
+import (
+	"container/heap"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	crawlJitter = 10 // percent of the status delay added or removed at random from each crawl time
+	scanFactor  = 4  // how many queue entries we will look at per crawl slot each startCrawlers run
+)
+
+// crawlQueue is a min-heap of nodes ordered by the time they are next due to be crawled.
+// It implements heap.Interface and must only be used while holding the seeder lock
+type crawlQueue []*node
+
+func (q crawlQueue) Len() int { return len(q) }
+
+func (q crawlQueue) Less(i, j int) bool { return q[i].nextCrawl.Before(q[j].nextCrawl) }
+
+func (q crawlQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].qIndex = i
+	q[j].qIndex = j
+}
+
+// Push adds a node to the end of the queue. Use heap.Push
+func (q *crawlQueue) Push(x interface{}) {
+	nd := x.(*node)
+	nd.qIndex = len(*q)
+	*q = append(*q, nd)
+}
+
+// Pop removes the last node from the queue. Use heap.Pop
+func (q *crawlQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	nd := old[n-1]
+	old[n-1] = nil
+	nd.qIndex = -1
+	*q = old[:n-1]
+	return nd
+}
+
+// queueNode places a node on the crawl queue or moves it to the correct position
+// if it is already queued
+func (s *dnsseeder) queueNode(nd *node) {
+	if nd.qIndex >= 0 {
+		heap.Fix(&s.queue, nd.qIndex)
+		return
+	}
+	heap.Push(&s.queue, nd)
+}
+
+// scheduleNode works out when a node should next be crawled based on its status,
+// the number of recent failures and some random jitter then queues the node
+func (s *dnsseeder) scheduleNode(nd *node) {
+
+	d := s.delay[nd.status]
+
+	// nodes that keep failing are backed off up to the limit for their status
+	if b := 1 + int64(nd.connectFails)/3; b < s.backoff[nd.status] {
+		d *= b
+	} else {
+		d *= s.backoff[nd.status]
+	}
+
+	// jitter stops nodes that were added together from always being crawled together
+	if j := d * crawlJitter / 100; j > 0 {
+		d += rand.Int63n(j*2+1) - j
+	}
+
+	nd.nextCrawl = nd.lastTry.Add(time.Second * time.Duration(d))
+	s.queueNode(nd)
+}
+
+// crawlsPerSource returns how many untried addresses from one source group can be
+// started each startCrawlers run so one peer or subnet can not take every crawl
+func (s *dnsseeder) crawlsPerSource() uint32 {
+	var total uint32
+	for _, m := range s.maxStart {
+		total += m
+	}
+	if total/sourceShare > 0 {
+		return total / sourceShare
+	}
+	return 1
+}
+
+// setStatus changes the status of a node and keeps the status totals up to date
+func (s *dnsseeder) setStatus(nd *node, status uint32) {
+	s.statusCount[nd.status]--
+	nd.status = status
+	s.statusCount[nd.status]++
+}
+
+// removeNode deletes a node from theList and the crawl queue. Caller must hold the write lock
+func (s *dnsseeder) removeNode(k nodeAddr) {
+	nd, ok := s.theList[k]
+	if ok == false {
+		return
+	}
+	if nd.qIndex >= 0 {
+		heap.Remove(&s.queue, nd.qIndex)
+	}
+	s.statusCount[nd.status]--
+	s.clearSlot(nd)
+	if c := s.groupCounts[nd.group]; c > 1 {
+		s.groupCounts[nd.group] = c - 1
+	} else {
+		delete(s.groupCounts, nd.group)
+	}
+	if c := s.srcCounts[nd.srcGroup]; c > 1 {
+		s.srcCounts[nd.srcGroup] = c - 1
+	} else {
+		delete(s.srcCounts, nd.srcGroup)
+	}
+
+	// remove the map entry and mark the old node as
+	// nil so garbage collector will remove it
+	s.theList[k] = nil
+	delete(s.theList, k)
+}
+
+// acquireCrawl reserves one of the global crawl slots shared by all seeders.
+// It returns false if they are all in use
+func acquireCrawl() bool {
+	if config.maxCrawls <= 0 {
+		atomic.AddInt32(&config.activeCrawls, 1)
+		return true
+	}
+	for {
+		c := atomic.LoadInt32(&config.activeCrawls)
+		if int(c) >= config.maxCrawls {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&config.activeCrawls, c, c+1) {
+			return true
+		}
+	}
+}
+
+// releaseCrawl returns a global crawl slot once a crawl has completed
+func releaseCrawl() {
+	atomic.AddInt32(&config.activeCrawls, -1)
+}
+
+/*
+
+ */
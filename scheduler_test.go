@@ -0,0 +1,130 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gombadi/dnsseeder/simpeer"
+)
+
+func TestCrawlQueue(t *testing.T) {
+
+	s := &dnsseeder{}
+	s.theList = make(map[nodeAddr]*node)
+
+	now := time.Now()
+	// add the nodes out of order so the heap has to sort them
+	offsets := []int{30, 10, 50, 20, 40}
+
+	for _, o := range offsets {
+		k := mustAddr(fmt.Sprintf("1.2.3.%d:1234", o))
+		nd := &node{nextCrawl: now.Add(time.Second * time.Duration(o)), qIndex: -1}
+		s.theList[k] = nd
+		s.statusCount[nd.status]++
+		s.queueNode(nd)
+	}
+
+	// remove one from the middle of the queue
+	s.removeNode(mustAddr("1.2.3.30:1234"))
+	if s.statusCount[statusRG] != 4 {
+		t.Errorf("statusRG count: %v expected: 4", s.statusCount[statusRG])
+	}
+
+	for _, expected := range []int{10, 20, 40, 50} {
+		nd := heap.Pop(&s.queue).(*node)
+		if got := int(nd.nextCrawl.Sub(now).Seconds()); got != expected {
+			t.Errorf("queue order - got node due in: %v expected: %v", got, expected)
+		}
+		if nd.qIndex != -1 {
+			t.Errorf("popped node still has a queue index: %v", nd.qIndex)
+		}
+	}
+}
+
+func TestScheduleNode(t *testing.T) {
+
+	s := &dnsseeder{
+		delay:   []int64{100, 100, 100, 100},
+		backoff: []int64{2, 1, 4, 1},
+	}
+
+	var tests = []struct {
+		status uint32
+		fails  uint32
+		min    int64
+		max    int64
+	}{
+		{statusRG, 0, 90, 110},
+		{statusRG, 9, 180, 220},
+		{statusCG, 9, 90, 110},
+		{statusWG, 5, 180, 220},
+		{statusWG, 20, 360, 440},
+		{statusNG, 20, 90, 110},
+	}
+
+	now := time.Now()
+	for _, atest := range tests {
+		nd := &node{status: atest.status, connectFails: atest.fails, lastTry: now, qIndex: -1}
+		s.scheduleNode(nd)
+
+		d := int64(nd.nextCrawl.Sub(now).Seconds())
+		if d < atest.min || d > atest.max {
+			t.Errorf("status: %s fails: %v delay: %v expected between %v and %v", status2str(atest.status), atest.fails, d, atest.min, atest.max)
+		}
+		if nd.qIndex < 0 {
+			t.Errorf("status: %s fails: %v node not queued", status2str(atest.status), atest.fails)
+		}
+	}
+}
+
+func TestCrawlBacklog(t *testing.T) {
+
+	// every crawl fails at once so each run is only limited by MaxStart
+	jnw := simNetwork()
+	jnw.MaxStart = []uint32{2, 4, 2, 2}
+	s := newSimSeeder(t, simpeer.NewNetwork(), jnw)
+
+	now := time.Now()
+	for i := 1; i <= 100; i++ {
+		nd := s.addNode(simAddr(fmt.Sprintf("20.%d.0.1", i), 8333), fmt.Sprintf("%d.0.0.0/16", i))
+		if nd == nil {
+			t.Fatalf("unable to add RG node %v", i)
+		}
+		nd.nextCrawl = now.Add(-time.Hour)
+		s.queueNode(nd)
+	}
+	var good []*node
+	for i := 1; i <= 3; i++ {
+		nd := s.addNode(simAddr(fmt.Sprintf("30.%d.0.1", i), 8333), "")
+		if nd == nil {
+			t.Fatalf("unable to add CG node %v", i)
+		}
+		s.setStatus(nd, statusCG)
+		s.makeTried(nd)
+		nd.nextCrawl = now.Add(-time.Minute)
+		s.queueNode(nd)
+		good = append(good, nd)
+	}
+
+	// the RG backlog is bigger than one scan but the due CG nodes must still be
+	// reached within a few runs
+	rc := make(chan *result)
+	for run := 0; run < 5; run++ {
+		s.startCrawlers(rc)
+		s.drainCrawls(rc)
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	for _, nd := range good {
+		if nd.crawlStart.IsZero() {
+			t.Errorf("due CG node %s not crawled behind the RG backlog", nd.na.IP)
+		}
+	}
+}
+
+/*
+
+ */
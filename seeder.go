@@ -1,10 +1,12 @@
 package main
 
 import (
+	"container/heap"
 	"fmt"
 	"log"
+	"math/big"
 	"net"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,13 +21,7 @@ const (
 	minPort = 0
 	maxPort = 65535
 
-	crawlDelay = 22 // seconds between start crawlwer ticks
-	auditDelay = 22 // minutes between audit channel ticks
-	dnsDelay   = 57 // seconds between updates to active dns record list
-
-	maxFails = 58 // max number of connect fails before we delete a node. Just over 24 hours(checked every 33 minutes)
-
-	maxTo = 250 // max seconds (4min 10 sec) for all comms to node to complete before we timeout
+	defMaxCrawls = 250 // default max number of crawls that can be running at once for a network
 )
 
 const (
@@ -47,31 +43,77 @@ const (
 )
 
 type dnsseeder struct {
-	id         wire.BitcoinNet  // Magic number - Unique ID for this network. Sent in header of all messages
-	theList    map[string]*node // the list of current nodes
-	mtx        sync.RWMutex     // protect thelist
-	dnsHost    string           // dns host we will serve results for this domain
-	name       string           // Short name for the network
-	desc       string           // Long description for the network
-	initialIPs []string         // Initial ip addresses to connect to and ask for addresses if we have no seeders
-	seeders    []string         // slice of seeders to pull ip addresses when starting this seeder
-	maxStart   []uint32         // max number of goroutines to start each run for each status type
-	delay      []int64          // number of seconds to wait before we connect to a known client for each status
-	counts     NodeCounts       // structure to hold stats for this seeder
-	pver       uint32           // minimum block height for the seeder
-	ttl        uint32           // DNS TTL to use for this seeder
-	maxSize    int              // max number of clients before we start restricting new entries
-	port       uint16           // default network port this seeder uses
+	id            wire.BitcoinNet        // Magic number - Unique ID for this network. Sent in header of all messages
+	codec         codec                  // reads and writes the messages for this network
+	theList       map[nodeAddr]*node     // the list of current nodes
+	mtx           sync.RWMutex           // protect thelist
+	dnsHost       string                 // dns host we will serve results for this domain
+	dnsNames      []dnsName              // all the dns names we serve. The first is dnsHost
+	name          string                 // Short name for the network
+	desc          string                 // Long description for the network
+	initialIPs    []string               // Initial ip addresses to connect to and ask for addresses if we have no seeders
+	seeders       []string               // slice of seeders to pull ip addresses when starting this seeder
+	rpc           *rpcSource             // local node to pull ip addresses from or nil
+	maxStart      []uint32               // max number of goroutines to start each run for each status type
+	delay         []int64                // number of seconds to wait before we connect to a known client for each status
+	backoff       []int64                // max multiplier applied to delay for each status when a client keeps failing
+	queue         crawlQueue             // nodes ordered by when they are next due to be crawled
+	counts        NodeCounts             // structure to hold stats for this seeder
+	statusCount   [maxStatusTypes]uint32 // number of nodes in theList at each status
+	activeCrawls  int                    // number of crawls currently running for this seeder
+	maxCrawls     int                    // max number of crawls that can be running at once for this seeder
+	dialer        dialer                 // used to connect to remote nodes
+	resolver      resolver               // used to lookup the other seeders for this network
+	bindIPv4      net.IP                 // local address for ipv4 connections or nil for any
+	bindIPv6      net.IP                 // local address for ipv6 connections or nil for any
+	disableIPv4   bool                   // do not accept or crawl ipv4 nodes
+	disableIPv6   bool                   // do not accept or crawl ipv6 nodes
+	allowPrivate  bool                   // accept private & loopback addresses for test networks
+	ipv6Down      bool                   // we have no ipv6 route so ipv6 nodes are not crawled
+	checkpoints   []checkpoint           // known blocks on the correct chain sorted by height
+	minChainWork  *big.Int               // min chain work a node must have or nil for no check
+	probeInterval int                    // hours between block probes of each node. 0 for no probes
+	probeTimeout  int                    // seconds a node has to return a probed block
+	pver          uint32                 // minimum block height for the seeder
+	ttl           uint32                 // DNS TTL to use for this seeder
+	maxSize       int                    // max number of clients before we start restricting new entries
+	crawlDelay    int                    // seconds between start crawler ticks
+	auditDelay    int                    // minutes between audit channel ticks
+	dnsDelay      int                    // seconds between updates to active dns record list
+	maxFails      int                    // max number of connect fails before we delete a NG node
+	maxTo         int                    // max seconds for all comms to a node to complete before we timeout
+	evictPolicy   string                 // evict or refuse when theList is full
+	evictCands    []*node                // eviction candidates sorted worst first
+	evictSorted   time.Time              // when the eviction candidates were sorted
+	groupCounts   map[string]int         // number of nodes in theList from each netgroup
+	maxPerGroup   int                    // max nodes from one netgroup in theList
+	srcCounts     map[string]int         // number of nodes in theList from each source group
+	maxDNSPerGrp  int                    // max nodes from one netgroup in one dns answer
+	port          uint16                 // default network port this seeder uses
+	tables        addrTables             // new and tried tables that limit what one source can add
+	stop          chan struct{}          // closed when a network reload removes this seeder
+	updated       chan struct{}          // signals runSeeder that a network reload changed the delays
+	stopped       chan struct{}          // closed when runSeeder has saved its final snapshot and returned
+	minGood       int                    // bootstrap from the other seeders when there are fewer statusCG nodes
+	booting       bool                   // a bootstrap lookup is running
+	bootDelay     time.Duration          // current wait between bootstrap attempts
+	nextBoot      time.Time              // earliest time for the next bootstrap attempt
+	boots         []bootEvent            // recent bootstrap events
 }
 
 type result struct {
-	nas        []*wire.NetAddress // slice of node addresses returned from a node
-	msg        *crawlError        // error string or nil if no problems
-	node       string             // theList key to the node that was crawled
-	version    int32              // remote node protocol version
-	services   wire.ServiceFlag   // remote client supported services
-	lastBlock  int32              // last block seen by the node
-	strVersion string             // remote client user agent
+	nas         []*wire.NetAddress // slice of node addresses returned from a node
+	msg         *crawlError        // error string or nil if no problems
+	node        nodeAddr           // theList key to the node that was crawled
+	version     int32              // remote node protocol version
+	services    wire.ServiceFlag   // remote client supported services
+	lastBlock   int32              // last block seen by the node
+	strVersion  string             // remote client user agent
+	checkChain  bool               // check the node is on the correct chain
+	chain       uint32             // result of the chain check
+	probe       bool               // probe the node for a historical block
+	probeStatus uint32             // result of the block probe
+	getAddr     bool               // ask the node for its addresses
 }
 
 // initCrawlers needs to be run before the startCrawlers so it can get
@@ -79,43 +121,15 @@ type result struct {
 // start the crawl process
 func (s *dnsseeder) initSeeder() {
 
-	// range over existing seeders for the network and get starting ip addresses from them
-	for _, aseeder := range s.seeders {
-		c := 0
-
-		if aseeder == "" {
-			continue
-		}
-		newRRs, err := net.LookupHost(aseeder)
-		if err != nil {
-			log.Printf("%s: unable to do initial lookup to seeder %s %v\n", s.name, aseeder, err)
-			continue
-		}
-
-		for _, ip := range newRRs {
-			if newIP := net.ParseIP(ip); newIP != nil {
-				// 1 at the end is the services flag
-				if x := s.addNa(wire.NewNetAddressIPPort(newIP, s.port, 1)); x == true {
-					c++
-				}
-			}
-		}
-		if config.verbose {
-			log.Printf("%s: completed import of %v addresses from %s\n", s.name, c, aseeder)
-		}
+	// a snapshot from our last run gives us a warm start without asking the other seeders
+	if n, err := s.loadNodes(); err != nil {
+		log.Printf("%s: unable to load node snapshot - %v\n", s.name, err)
+	} else if n > 0 {
+		return
 	}
 
-	// load ip addresses into system and start crawling from them
-	if len(s.theList) == 0 && len(s.initialIPs) > 0 {
-		for _, initialIP := range s.initialIPs {
-			if newIP := net.ParseIP(initialIP); newIP != nil {
-				// 1 at the end is the services flag
-				if x := s.addNa(wire.NewNetAddressIPPort(newIP, s.port, 1)); x == true {
-					log.Printf("%s: crawling with initial IP %s \n", s.name, initialIP)
-				}
-			}
-		}
-	}
+	// get starting ip addresses from the other seeders for the network and the local node
+	s.addBootstrap(s.lookupSeeders("startup", s.seeders, s.rpc))
 
 	if len(s.theList) == 0 {
 		log.Printf("%s: Error: No ip addresses from seeders so I have nothing to crawl.\n", s.name)
@@ -136,47 +150,103 @@ func (s *dnsseeder) runSeeder(done <-chan struct{}, wg *sync.WaitGroup) {
 	// receive the results from the crawl goroutines
 	resultsChan := make(chan *result)
 
+	// find out if we can reach ipv6 nodes before we start crawling them
+	s.checkIPv6()
+
 	// load data from other seeders so we can start crawling nodes
 	s.initSeeder()
 
 	// start initial scan now so we don't have to wait for the timers to fire
 	s.startCrawlers(resultsChan)
 
+	// give the crawlers time to find good nodes before the watchdog can bootstrap again
+	bootChan := make(chan *bootResult, 1)
+	s.mtx.Lock()
+	s.nextBoot = time.Now().Add(time.Minute * bootMinDelay)
+	s.mtx.Unlock()
+
 	// create timing channels for regular tasks
-	auditChan := time.NewTicker(time.Minute * auditDelay).C
-	crawlChan := time.NewTicker(time.Second * crawlDelay).C
-	dnsChan := time.NewTicker(time.Second * dnsDelay).C
+	auditTicker, crawlTicker, dnsTicker := s.newTickers()
+
+	// only take snapshots if we have somewhere to save them
+	var snapChan <-chan time.Time
+	if s.snapFile() != "" {
+		snapChan = time.NewTicker(time.Minute * snapDelay).C
+	}
 
 	dowhile := true
 	for dowhile == true {
 		select {
+		case <-s.updated:
+			// a network reload changed the delays so restart the tickers
+			auditTicker.Stop()
+			crawlTicker.Stop()
+			dnsTicker.Stop()
+			auditTicker, crawlTicker, dnsTicker = s.newTickers()
 		case r := <-resultsChan:
 			// process a results structure from a crawl
 			s.processResult(r)
-		case <-dnsChan:
+		case <-dnsTicker.C:
 			// update the system with the latest selection of dns records
 			s.loadDNS()
-		case <-auditChan:
+		case <-auditTicker.C:
 			// keep theList clean and tidy
 			s.auditNodes()
-		case <-crawlChan:
+			// ipv6 connectivity may have changed since the last audit
+			s.checkIPv6()
+		case <-crawlTicker.C:
 			// start a scan to crawl nodes
 			s.startCrawlers(resultsChan)
+			// ask the other seeders for more nodes if we are running low
+			if reason := s.needBootstrap(time.Now()); reason != "" {
+				s.startBootstrap(reason, bootChan)
+			}
+		case br := <-bootChan:
+			// add the nodes from a bootstrap lookup
+			s.bootEnd(br)
+		case <-snapChan:
+			// save theList so we can warm start after a restart
+			if err := s.saveNodes(); err != nil {
+				log.Printf("%s: unable to save node snapshot - %v\n", s.name, err)
+			}
+		case <-s.stop:
+			// a network reload removed this seeder so let the running crawls finish
+			// before we shutdown so their crawl slots are released
+			s.drainCrawls(resultsChan)
+			dowhile = false
 		case <-done:
 			// done channel closed so exit the select and shutdown the seeder
 			dowhile = false
 		}
 	}
+	auditTicker.Stop()
+	crawlTicker.Stop()
+	dnsTicker.Stop()
 	fmt.Printf("shutting down seeder: %s\n", s.name)
+	if err := s.saveNodes(); err != nil {
+		log.Printf("%s: unable to save node snapshot - %v\n", s.name, err)
+	}
+	// a seeder restarted by a network reload waits for this final snapshot
+	close(s.stopped)
 	// end the goroutine & defer will call wg.Done()
 }
 
-// startCrawlers is called on a time basis to start maxcrawlers new
-// goroutines if there are spare goroutine slots available
-func (s *dnsseeder) startCrawlers(resultsChan chan *result) {
-
+// newTickers returns the tickers for the regular seeder tasks using the current delays
+func (s *dnsseeder) newTickers() (audit, crawl, dns *time.Ticker) {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
+	audit = time.NewTicker(time.Minute * time.Duration(s.auditDelay))
+	crawl = time.NewTicker(time.Second * time.Duration(s.crawlDelay))
+	dns = time.NewTicker(time.Second * time.Duration(s.dnsDelay))
+	return audit, crawl, dns
+}
+
+// startCrawlers is called on a time basis to start new crawls for the nodes at the
+// front of the crawl queue if there are spare crawl slots available
+func (s *dnsseeder) startCrawlers(resultsChan chan *result) {
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
 
 	tcount := uint32(len(s.theList))
 	if tcount == 0 {
@@ -188,40 +258,102 @@ func (s *dnsseeder) startCrawlers(resultsChan chan *result) {
 
 	started := make([]uint32, maxStatusTypes)
 	totals := make([]uint32, maxStatusTypes)
+	copy(totals, s.statusCount[:])
+
+	// limit how much of the queue we look at so a large backlog of nodes at a
+	// status that has already reached maxStart does not make this O(n)
+	maxScan := 0
+	for _, m := range s.maxStart {
+		maxScan += int(m) * scanFactor
+	}
 
-	// range on a map will not return items in the same order each time
-	// so this is a random'ish selection
-	for _, nd := range s.theList {
+	now := time.Now()
+	skipped := []*node{}
+	srcStarted := make(map[string]uint32)
+	perSrc := s.crawlsPerSource()
 
-		totals[nd.status]++
+	for scanned := 0; s.queue.Len() > 0 && scanned < maxScan; scanned++ {
 
-		if nd.crawlActive == true {
+		// the queue is ordered by next crawl time so once we find a node that
+		// is not due there is no more work to do this run
+		if s.queue[0].nextCrawl.After(now) {
+			break
+		}
+
+		// do we have a spare crawl slot for this network
+		if s.activeCrawls >= s.maxCrawls {
+			break
+		}
+
+		nd := heap.Pop(&s.queue).(*node)
+
+		// without ipv6 connectivity there is no point trying ipv6 nodes so check them again later
+		if s.ipv6Down && nd.na.IP.To4() == nil {
+			nd.nextCrawl = now.Add(time.Second * time.Duration(s.delay[nd.status]))
+			skipped = append(skipped, nd)
+			continue
+		}
+
+		// the operator has banned this node since it was added so do not crawl it.
+		// It stays in theList so it shows on the banned page and is crawled if the ban ends
+		if b, reason := s.isBanned(nd.na.IP); b {
+			nd.statusStr = "banned: " + reason
+			nd.nextCrawl = now.Add(time.Second * time.Duration(s.delay[nd.status]))
+			skipped = append(skipped, nd)
 			continue
 		}
 
-		// do we already have enough started at this status
+		// do we already have enough started at this status. The node is moved behind
+		// the nodes due now so a large backlog at one status can not keep the
+		// nodes at the other statuses out of the scan
 		if started[nd.status] >= s.maxStart[nd.status] {
+			nd.nextCrawl = now.Add(time.Second * time.Duration(s.crawlDelay))
+			skipped = append(skipped, nd)
 			continue
 		}
 
-		// don't crawl a node to quickly
-		if (time.Now().Unix() - s.delay[nd.status]) <= nd.lastTry.Unix() {
+		// do not let the untried addresses from one source take every crawl this run
+		if nd.table != tableTried && srcStarted[nd.srcGroup] >= perSrc {
+			nd.nextCrawl = now.Add(time.Second * time.Duration(s.crawlDelay))
+			skipped = append(skipped, nd)
 			continue
 		}
 
+		// do we have a spare crawl slot across all networks
+		if acquireCrawl() == false {
+			skipped = append(skipped, nd)
+			break
+		}
+
 		// all looks good so start a go routine to crawl the remote node
 		nd.crawlActive = true
-		nd.crawlStart = time.Now()
+		nd.crawlStart = now
+		s.activeCrawls++
+
+		res := &result{
+			node:       nd.addr,
+			checkChain: s.needChainCheck(nd),
+			probe:      s.needProbe(nd),
+			getAddr:    s.wantAddrs(),
+		}
 
-		go crawlNode(resultsChan, s, nd)
+		go crawlNode(resultsChan, s, res)
 		started[nd.status]++
+		if nd.table != tableTried {
+			srcStarted[nd.srcGroup]++
+		}
+	}
+
+	// put the nodes we did not start back on the queue
+	for _, nd := range skipped {
+		s.queueNode(nd)
 	}
 
 	// update the global stats in another goroutine to free the main goroutine
 	// for other work
 	go updateNodeCounts(s, tcount, started, totals)
 
-	// returns and read lock released
+	// returns and lock released
 }
 
 // processResult will add new nodes to the list and update the status of the crawled node
@@ -232,6 +364,10 @@ func (s *dnsseeder) processResult(r *result) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
+	// free the crawl slots even if the node has been removed while it was being crawled
+	s.activeCrawls--
+	releaseCrawl()
+
 	if _, ok := s.theList[r.node]; ok {
 		nd = s.theList[r.node]
 	} else {
@@ -240,33 +376,49 @@ func (s *dnsseeder) processResult(r *result) {
 	}
 
 	// now nd has been set to a valid pointer we can use it in a defer
-	defer crawlEnd(nd)
+	defer s.crawlEnd(nd)
 
 	// msg is a crawlerror or nil
+	if r.msg != nil && nd.na.IP.To4() == nil && isNoRoute(r.msg.Err) {
+		// the failure is due to our ipv6 connectivity so don't hold it against the node
+		nd.lastTry = time.Now()
+		nd.statusStr = r.msg.Error()
+		s.ipv6Down = true
+		if config.verbose {
+			log.Printf("%s: no ipv6 route crawling node: %s ipv6 crawls paused\n", s.name, r.node)
+		}
+		return
+	}
+
 	if r.msg != nil {
 		// update the fact that we have not connected to this node
+		nd.updateStats(false)
 		nd.lastTry = time.Now()
 		nd.connectFails++
 		nd.statusStr = r.msg.Error()
 
-		// update the status of this failed node
-		switch nd.status {
-		case statusRG:
-			// if we are full then any RG failures will skip directly to NG
-			if len(s.theList) > s.maxSize {
-				nd.status = statusNG // not able to connect to this node so ignore
-			} else {
+		if r.chain > chainOK {
+			// the node is not on the correct chain so make sure it is never served
+			nd.chainStatus = r.chain
+			nd.chainChecked = nd.lastTry
+			nd.statusStr = chain2str(r.chain) + " " + nd.statusStr
+			s.setStatus(nd, statusNG)
+		} else {
+			// update the status of this failed node
+			switch nd.status {
+			case statusRG:
+				// a full list is left to eviction to make room so failures are rated as normal
 				if nd.rating += 25; nd.rating > 30 {
-					nd.status = statusWG
+					s.setStatus(nd, statusWG)
+				}
+			case statusCG:
+				if nd.rating += 25; nd.rating >= 50 {
+					s.setStatus(nd, statusWG)
+				}
+			case statusWG:
+				if nd.rating += 15; nd.rating >= 100 {
+					s.setStatus(nd, statusNG) // not able to connect to this node so ignore
 				}
-			}
-		case statusCG:
-			if nd.rating += 25; nd.rating >= 50 {
-				nd.status = statusWG
-			}
-		case statusWG:
-			if nd.rating += 15; nd.rating >= 100 {
-				nd.status = statusNG // not able to connect to this node so ignore
 			}
 		}
 		// no more to do so return which will shutdown the goroutine & call
@@ -274,8 +426,7 @@ func (s *dnsseeder) processResult(r *result) {
 		if config.verbose {
 			log.Printf("%s: failed crawl node: %s s:r:f: %v:%v:%v %s\n",
 				s.name,
-				net.JoinHostPort(nd.na.IP.String(),
-					strconv.Itoa(int(nd.na.Port))),
+				nd.addr,
 				nd.status,
 				nd.rating,
 				nd.connectFails,
@@ -285,7 +436,9 @@ func (s *dnsseeder) processResult(r *result) {
 	}
 
 	// succesful connection and addresses received so mark status
-	nd.status = statusCG
+	s.setStatus(nd, statusCG)
+	s.makeTried(nd)
+	nd.updateStats(true)
 	cs := nd.lastConnect
 	nd.rating = 0
 	nd.connectFails = 0
@@ -297,18 +450,30 @@ func (s *dnsseeder) processResult(r *result) {
 	nd.services = r.services
 	nd.lastBlock = r.lastBlock
 	nd.strVersion = r.strVersion
+	if r.chain == chainOK {
+		nd.chainStatus = chainOK
+		nd.chainChecked = nd.lastConnect
+	}
+	if r.probeStatus != probeUnknown {
+		nd.probeStatus = r.probeStatus
+		nd.probed = nd.lastConnect
+	}
+	if r.services&wire.SFNodeNetwork == 0 {
+		// no longer claims to be a full node so any old probe result does not apply
+		nd.probeStatus = probeUnknown
+	}
 
 	added := 0
 
-	// if we are full then skip adding more possible clients
-	if len(s.theList) < s.maxSize {
+	// if we are full then skip adding more possible clients unless they can replace worse nodes
+	if len(s.theList) < s.maxSize || s.evictPolicy == evictWorst {
 		// do not accept more than one third of maxSize addresses from one node
 		oneThird := int(float64(s.maxSize / 3))
 
 		// loop through all the received network addresses and add to thelist if not present
 		for _, na := range r.nas {
 			// a new network address so add to the system
-			if x := s.addNa(na); x == true {
+			if x := s.addNa(na, nd.group); x == true {
 				if added++; added > oneThird {
 					break
 				}
@@ -319,8 +484,7 @@ func (s *dnsseeder) processResult(r *result) {
 	if config.verbose {
 		log.Printf("%s: crawl done: node: %s s:r:f: %v:%v:%v addr: %v:%v CrawlTime: %s Last connect: %v ago\n",
 			s.name,
-			net.JoinHostPort(nd.na.IP.String(),
-				strconv.Itoa(int(nd.na.Port))),
+			nd.addr,
 			nd.status,
 			nd.rating,
 			nd.connectFails,
@@ -332,39 +496,134 @@ func (s *dnsseeder) processResult(r *result) {
 }
 
 // crawlEnd is run as a defer to make sure node status is correctly updated
-func crawlEnd(nd *node) {
+// and the node is scheduled for its next crawl
+func (s *dnsseeder) crawlEnd(nd *node) {
 	nd.crawlActive = false
+	s.scheduleNode(nd)
 }
 
-// addNa validates and adds a network address to theList
-func (s *dnsseeder) addNa(nNa *wire.NetAddress) bool {
+// addNa validates and adds a network address reported by a source group to theList
+func (s *dnsseeder) addNa(nNa *wire.NetAddress, src string) bool {
 
-	if len(s.theList) > s.maxSize {
+	// if the reported timestamp suggests the netaddress has not been seen in the last 24 hours
+	// then ignore this netaddress
+	if trustedSource(src) == false && (time.Now().Add(-(time.Hour * 24))).After(nNa.Timestamp) {
 		return false
 	}
 
-	// generate the key and add to theList
-	k := net.JoinHostPort(nNa.IP.String(), strconv.Itoa(int(nNa.Port)))
+	return s.addNode(nNa, src) != nil
+}
+
+// trustedSource returns true for the source groups the operator gave us directly. An
+// imported file can be days old and the local node keeps addresses it has not seen for
+// a while so the last seen time of their addresses is not checked
+func trustedSource(src string) bool {
+	return strings.HasPrefix(src, srcImport) || strings.HasPrefix(src, srcRPC)
+}
+
+// addNode validates a network address from a source group and adds it to theList and
+// the new table as a new statusRG node. It returns nil if the address was not added
+func (s *dnsseeder) addNode(nNa *wire.NetAddress, src string) *node {
+
+	// generate the canonical key so mapped and plain ipv4 addresses are the same node
+	k := naAddr(nNa)
 
 	if _, dup := s.theList[k]; dup == true {
-		return false
+		return nil
+	}
+	if s.allowAddr(nNa) == false {
+		return nil
+	}
+
+	// do not let one netgroup fill theList
+	g := netGroup(nNa.IP)
+	if s.maxPerGroup > 0 && s.groupCounts[g] >= s.maxPerGroup {
+		return nil
+	}
+
+	// do not let one peer or subnet fill theList with the addresses it reports
+	if s.srcCounts[src] >= s.maxPerSource() {
+		return nil
+	}
+
+	nt := s.newNode(nNa, src)
+
+	// the address must win a slot in the new table for its source group. The node
+	// holding the slot is only removed once we know the address will be added
+	holder, ok := s.newSlotHolder(nt)
+	if ok == false {
+		return nil
+	}
+
+	// theList is full so make room by evicting a worse node or refuse the address
+	size := len(s.theList)
+	if holder != nil {
+		size--
+	}
+	if size > s.maxSize {
+		if s.evictPolicy != evictWorst || s.evictNode() == false {
+			return nil
+		}
 	}
+
+	if s.placeNew(nt) == false {
+		return nil
+	}
+
+	// add the new node details to theList and queue it for an initial crawl
+	s.insertNode(nt)
+	return nt
+}
+
+// allowAddr returns false for an address we have been told not to crawl or that can
+// never be served
+func (s *dnsseeder) allowAddr(nNa *wire.NetAddress) bool {
+
 	if nNa.Port <= minPort || nNa.Port >= maxPort {
 		return false
 	}
 
-	// if the reported timestamp suggests the netaddress has not been seen in the last 24 hours
-	// then ignore this netaddress
-	if (time.Now().Add(-(time.Hour * 24))).After(nNa.Timestamp) {
+	// ignore address families we have been told not to crawl
+	if x := nNa.IP.To4(); (x != nil && s.disableIPv4) || (x == nil && s.disableIPv6) {
 		return false
 	}
 
+	// never crawl or serve addresses that are not publicly routable
+	if r := s.routable(nNa.IP); r != routeOK {
+		s.countReject(r)
+		return false
+	}
+
+	// the operator does not want this address crawled
+	if b, _ := s.isBanned(nNa.IP); b {
+		s.counts.mtx.Lock()
+		s.counts.Banned++
+		s.counts.mtx.Unlock()
+		return false
+	}
+	return true
+}
+
+// newNode returns a statusRG node for an address from a source group. It is not
+// in theList or a table
+func (s *dnsseeder) newNode(nNa *wire.NetAddress, src string) *node {
+
+	k := naAddr(nNa)
+
+	// store ipv4 addresses in their 4 byte form
+	nNa.IP = k.IP()
+
 	nt := node{
 		na:          nNa,
+		addr:        k,
 		lastConnect: time.Now(),
+		nextCrawl:   time.Now(),
 		version:     0,
 		status:      statusRG,
 		dnsType:     dnsV4Std,
+		group:       netGroup(nNa.IP),
+		srcGroup:    src,
+		qIndex:      -1,
 	}
 
 	// select the dns type based on the remote address type and port
@@ -384,18 +643,27 @@ func (s *dnsseeder) addNa(nNa *wire.NetAddress) bool {
 		if nNa.Port != s.port {
 			nt.dnsType = dnsV4Non
 
-			// force ipv4 address into a 4 byte buffer
-			nt.na.IP = nt.na.IP.To4()
-
 			// produce the nonstdIP
 			nt.nonstdIP = getNonStdIP(nt.na.IP, nt.na.Port)
 		}
 	}
+	return &nt
+}
 
-	// add the new node details to theList
-	s.theList[k] = &nt
-
-	return true
+// insertNode adds a node to theList and the status, netgroup and source counts then
+// queues it for a crawl
+func (s *dnsseeder) insertNode(nd *node) {
+	s.theList[nd.addr] = nd
+	s.statusCount[nd.status]++
+	if s.groupCounts == nil {
+		s.groupCounts = make(map[string]int)
+	}
+	s.groupCounts[nd.group]++
+	if s.srcCounts == nil {
+		s.srcCounts = make(map[string]int)
+	}
+	s.srcCounts[nd.srcGroup]++
+	s.queueNode(nd)
 }
 
 // getNonStdIP is given an IP address and a port and returns a fake IP address
@@ -435,20 +703,20 @@ func (s *dnsseeder) auditNodes() {
 
 	c := 0
 
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
 	// set this early so for this audit run all NG clients will be purged
 	// and space will be made for new, possible CG clients
 	iAmFull := len(s.theList) > s.maxSize
 
 	// cgGoal is 75% of the max statusCG clients we can crawl with the current network delay & maxStart settings.
 	// This allows us to cycle statusCG users to keep the list fresh
-	cgGoal := int(float64(float64(s.delay[statusCG]/crawlDelay)*float64(s.maxStart[statusCG])) * 0.75)
+	cgGoal := int(float64(float64(s.delay[statusCG]/int64(s.crawlDelay))*float64(s.maxStart[statusCG])) * 0.75)
 	cgCount := 0
 
 	log.Printf("%s: Audit start. statusCG Goal: %v System Uptime: %s\n", s.name, cgGoal, time.Since(config.uptime).String())
 
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-
 	for k, nd := range s.theList {
 
 		if nd.crawlActive == true {
@@ -464,16 +732,13 @@ func (s *dnsseeder) auditNodes() {
 		}
 
 		// Audit task is to remove node that we have not been able to connect to
-		if nd.status == statusNG && nd.connectFails > maxFails {
+		if nd.status == statusNG && int(nd.connectFails) > s.maxFails {
 			if config.verbose {
 				log.Printf("%s: purging node %s after %v failed connections\n", s.name, k, nd.connectFails)
 			}
 
 			c++
-			// remove the map entry and mark the old node as
-			// nil so garbage collector will remove it
-			s.theList[k] = nil
-			delete(s.theList, k)
+			s.removeNode(k)
 		}
 
 		// If seeder is full then remove old NG clients and fill up with possible new CG clients
@@ -483,10 +748,7 @@ func (s *dnsseeder) auditNodes() {
 			}
 
 			c++
-			// remove the map entry and mark the old node as
-			// nil so garbage collector will remove it
-			s.theList[k] = nil
-			delete(s.theList, k)
+			s.removeNode(k)
 		}
 
 		// check if we need to purge statusCG to freshen the list
@@ -498,10 +760,7 @@ func (s *dnsseeder) auditNodes() {
 				}
 
 				c++
-				// remove the map entry and mark the old node as
-				// nil so garbage collector will remove it
-				s.theList[k] = nil
-				delete(s.theList, k)
+				s.removeNode(k)
 			}
 		}
 	}
@@ -518,6 +777,8 @@ func (s *dnsseeder) loadDNS() {
 
 // getSeederByName returns a pointer to the seeder based on its name or nil if not found
 func getSeederByName(name string) *dnsseeder {
+	config.smtx.RLock()
+	defer config.smtx.RUnlock()
 	for _, s := range config.seeders {
 		if s.name == name {
 			return s
@@ -526,19 +787,36 @@ func getSeederByName(name string) *dnsseeder {
 	return nil
 }
 
-// isDuplicateSeeder returns true if the seeder details already exist in the application
-func isDuplicateSeeder(s *dnsseeder) (bool, error) {
+// isDuplicateSeeder returns true if the seeder details already exist in seeders
+func isDuplicateSeeder(s *dnsseeder, seeders map[string]*dnsseeder) (bool, error) {
+
+	if dups := duplicateSeeder(s, seeders); len(dups) > 0 {
+		return true, dups[0]
+	}
+	return false, nil
+}
+
+// duplicateSeeder returns an error for each detail of the seeder that is already
+// used by one of seeders
+func duplicateSeeder(s *dnsseeder, seeders map[string]*dnsseeder) []error {
 
-	// check for duplicate seeders with the same details
-	for _, v := range config.seeders {
+	var dups []error
+	for _, v := range seeders {
+		if v.name == s.name {
+			dups = append(dups, fmt.Errorf("Duplicate network name %s", s.name))
+		}
 		if v.id == s.id {
-			return true, fmt.Errorf("Duplicate Magic id. Already loaded for %s so can not be used for %s", v.id, v.name, s.name)
+			dups = append(dups, fmt.Errorf("Duplicate Magic id 0x%08x. Already loaded for %s so can not be used for %s", uint32(v.id), v.name, s.name))
 		}
-		if v.dnsHost == s.dnsHost {
-			return true, fmt.Errorf("Duplicate DNS names. Already loaded %s for %s so can not be used for %s", v.dnsHost, v.name, s.name)
+		for _, vn := range v.dnsNames {
+			for _, sn := range s.dnsNames {
+				if vn.host == sn.host {
+					dups = append(dups, fmt.Errorf("Duplicate DNS names. Already loaded %s for %s so can not be used for %s", vn.host, v.name, s.name))
+				}
+			}
 		}
 	}
-	return false, nil
+	return dups
 }
 
 /*
@@ -44,7 +44,7 @@ func TestAddnNa(t *testing.T) {
 		pver:    1234,
 		maxSize: 1,
 	}
-	s.theList = make(map[string]*node)
+	s.theList = make(map[nodeAddr]*node)
 
 	for _, atest := range td {
 		// Test NewNetAddress.
@@ -52,24 +52,24 @@ func TestAddnNa(t *testing.T) {
 			IP:   net.ParseIP(atest.ip),
 			Port: atest.port,
 		}
-		na, _ := wire.NewNetAddress(tcpAddr, 0)
+		na := wire.NewNetAddress(tcpAddr, 0)
 		ndName := net.JoinHostPort(na.IP.String(), strconv.Itoa(int(na.Port)))
 
-		result := s.addNa(na)
+		result := s.addNa(na, "")
 		if result != true {
 			t.Errorf("failed to create new node: %s", ndName)
 		}
-		if s.theList[ndName].dnsType != atest.dnsType {
-			t.Errorf("node: %s dnsType:%v expected: %v", ndName, s.theList[ndName].dnsType, atest.dnsType)
+		if s.theList[mustAddr(ndName)].dnsType != atest.dnsType {
+			t.Errorf("node: %s dnsType:%v expected: %v", ndName, s.theList[mustAddr(ndName)].dnsType, atest.dnsType)
 		}
 	}
 
 	tcpAddr := &net.TCPAddr{
-		IP:   net.ParseIP("127.0.0.1"),
+		IP:   net.ParseIP("5.6.7.8"),
 		Port: 1234,
 	}
-	na, _ := wire.NewNetAddress(tcpAddr, 0)
-	result := s.addNa(na)
+	na := wire.NewNetAddress(tcpAddr, 0)
+	result := s.addNa(na, "")
 
 	if result != false {
 		t.Errorf("node added but should have failed as seeder full: %s", net.JoinHostPort(na.IP.String(), strconv.Itoa(int(na.Port))))
@@ -79,8 +79,8 @@ func TestAddnNa(t *testing.T) {
 		IP:   net.ParseIP("1.2.3.4"),
 		Port: 28333,
 	}
-	na, _ = wire.NewNetAddress(tcpAddr, 0)
-	result = s.addNa(na)
+	na = wire.NewNetAddress(tcpAddr, 0)
+	result = s.addNa(na, "")
 
 	if result != false {
 		t.Errorf("node added but should have failed as duplicate: %s", net.JoinHostPort(na.IP.String(), strconv.Itoa(int(na.Port))))
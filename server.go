@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// envConfig is the environment variable that names the server config file when -config is not used
+const envConfig = "DNSSEEDER_CONFIG"
+
+// JServer is the exported struct that is read from the server config file. Each
+// field sets the same value as a command line flag
+type JServer struct {
+	Listen    string            `json:",omitempty"` // dns server [address:]port. -p
+	Web       string            `json:",omitempty"` // web interface [address:]port. -w
+	DataDir   string            `json:",omitempty"` // node snapshot directory. -datadir
+	ASMap     string            `json:",omitempty"` // asmap file. -asmap
+	BanList   string            `json:",omitempty"` // ban list file. -banlist
+	Import    []string          `json:",omitempty"` // [network=]file to import at startup. -import
+	MaxCrawls *int              `json:",omitempty"` // max crawls across all networks. -maxcrawls
+	Log       JLog              `json:",omitempty"`
+	Networks  []json.RawMessage `json:",omitempty"` // network file names or inline networks
+}
+
+// JLog holds the logging settings from the server config file
+type JLog struct {
+	File    string `json:",omitempty"` // file to append the log to. -logfile
+	Verbose *bool  `json:",omitempty"` // -v
+	Debug   *bool  `json:",omitempty"` // -d
+	Stats   *bool  `json:",omitempty"` // -s
+}
+
+// envSettings are the environment variables that can set a flag. They are used
+// over the server config file but not over a flag on the command line
+var envSettings = []struct {
+	flag string
+	env  string
+}{
+	{"netfile", "DNSSEEDER_NETFILE"},
+	{"p", "DNSSEEDER_LISTEN"},
+	{"w", "DNSSEEDER_WEB"},
+	{"datadir", "DNSSEEDER_DATADIR"},
+	{"asmap", "DNSSEEDER_ASMAP"},
+	{"banlist", "DNSSEEDER_BANLIST"},
+	{"import", "DNSSEEDER_IMPORT"},
+	{"maxcrawls", "DNSSEEDER_MAXCRAWLS"},
+	{"logfile", "DNSSEEDER_LOGFILE"},
+	{"v", "DNSSEEDER_VERBOSE"},
+	{"d", "DNSSEEDER_DEBUG"},
+	{"s", "DNSSEEDER_STATS"},
+}
+
+// loadServerConfig reads the server config file. Fields that are not known are an error
+func loadServerConfig(fName string) (*JServer, error) {
+
+	data, err := ioutil.ReadFile(fName)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading server config file: %v", err)
+	}
+	var jsrv JServer
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&jsrv); err != nil {
+		return nil, fmt.Errorf("Error decoding server config file %s: %v", fName, err)
+	}
+	return &jsrv, nil
+}
+
+// flagValues returns the flag values set in the server config file by flag name
+func (jsrv *JServer) flagValues() map[string]string {
+
+	fv := make(map[string]string)
+	str := func(name, v string) {
+		if v != "" {
+			fv[name] = v
+		}
+	}
+	bl := func(name string, v *bool) {
+		if v != nil {
+			fv[name] = strconv.FormatBool(*v)
+		}
+	}
+	str("p", jsrv.Listen)
+	str("w", jsrv.Web)
+	str("datadir", jsrv.DataDir)
+	str("asmap", jsrv.ASMap)
+	str("banlist", jsrv.BanList)
+	str("import", strings.Join(jsrv.Import, ","))
+	if jsrv.MaxCrawls != nil {
+		fv["maxcrawls"] = strconv.Itoa(*jsrv.MaxCrawls)
+	}
+	str("logfile", jsrv.Log.File)
+	bl("v", jsrv.Log.Verbose)
+	bl("d", jsrv.Log.Debug)
+	bl("s", jsrv.Log.Stats)
+	return fv
+}
+
+// applySettings sets the flags that were not given on the command line from the
+// environment and then the server config file. The order is flags, environment,
+// server config file and then the flag defaults. It returns the server config
+// file name, or an empty string if there is none
+func applySettings(fs *flag.FlagSet, getenv func(string) string) (string, error) {
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	cfgFile := fs.Lookup("config").Value.String()
+	if set["config"] == false && getenv(envConfig) != "" {
+		cfgFile = getenv(envConfig)
+	}
+
+	if cfgFile != "" {
+		jsrv, err := loadServerConfig(cfgFile)
+		if err != nil {
+			return "", err
+		}
+		for name, v := range jsrv.flagValues() {
+			if set[name] == false {
+				if err = fs.Set(name, v); err != nil {
+					return "", fmt.Errorf("Error in server config file %s - invalid value %s for -%s: %v", cfgFile, v, name, err)
+				}
+			}
+		}
+	}
+
+	for _, es := range envSettings {
+		if v := getenv(es.env); v != "" && set[es.flag] == false {
+			if err := fs.Set(es.flag, v); err != nil {
+				return "", fmt.Errorf("Error - invalid value %s in %s: %v", v, es.env, err)
+			}
+		}
+	}
+	return cfgFile, nil
+}
+
+// serverNetworks loads the networks listed in the server config file. Each entry is
+// either the name of a network file, relative to the server config file, or a
+// network written inline in the same format as a network file
+func serverNetworks(cfgFile string) (map[string]*dnsseeder, []string, error) {
+
+	jsrv, err := loadServerConfig(cfgFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(jsrv.Networks) == 0 {
+		return nil, nil, fmt.Errorf("Error - no networks in server config file %s", cfgFile)
+	}
+
+	var specs []netSpec
+	for i, raw := range jsrv.Networks {
+		var fName string
+		if err = json.Unmarshal(raw, &fName); err == nil {
+			if filepath.IsAbs(fName) == false {
+				fName = filepath.Join(filepath.Dir(cfgFile), fName)
+			}
+			specs = append(specs, netSpec{name: fName})
+			continue
+		}
+		specs = append(specs, netSpec{name: fmt.Sprintf("%s network %d", cfgFile, i+1), data: raw})
+	}
+	return loadNetworkSpecs(specs)
+}
+
+// dnsListenAddr returns the address for the dns server. A port on its own listens
+// on all addresses
+func dnsListenAddr(listen string) string {
+	if strings.Contains(listen, ":") {
+		return listen
+	}
+	return ":" + listen
+}
+
+// webListenAddr returns the address for the web interface. A port on its own listens
+// on localhost only
+func webListenAddr(listen string) string {
+	if strings.Contains(listen, ":") {
+		return listen
+	}
+	return "127.0.0.1:" + listen
+}
+
+/*
+
+ */
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplySettings(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfgFile := filepath.Join(dir, "server.json")
+	j := `{"Listen": "127.0.0.1:53", "Web": "8080", "DataDir": "/var/lib/dnsseeder", "MaxCrawls": 50,
+		"Log": {"Verbose": true, "File": "/var/log/dnsseeder.log"}}`
+	if err = ioutil.WriteFile(cfgFile, []byte(j), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a flag set with the same flags as main
+	newFlags := func() (*flag.FlagSet, map[string]*string) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		vals := map[string]*string{
+			"config":  fs.String("config", "", ""),
+			"netfile": fs.String("netfile", "", ""),
+			"p":       fs.String("p", "8053", ""),
+			"w":       fs.String("w", "", ""),
+			"datadir": fs.String("datadir", "", ""),
+			"asmap":   fs.String("asmap", "", ""),
+			"banlist": fs.String("banlist", "", ""),
+			"import":  fs.String("import", "", ""),
+			"logfile": fs.String("logfile", "", ""),
+		}
+		fs.Int("maxcrawls", 1000, "")
+		fs.Bool("v", false, "")
+		fs.Bool("d", false, "")
+		fs.Bool("s", false, "")
+		return fs, vals
+	}
+	get := func(fs *flag.FlagSet, name string) string { return fs.Lookup(name).Value.String() }
+
+	env := map[string]string{}
+	getenv := func(k string) string { return env[k] }
+
+	// flags over the environment over the server config file over the defaults
+	fs, _ := newFlags()
+	fs.Parse([]string{"-config", cfgFile, "-p", "5353"})
+	env["DNSSEEDER_WEB"] = "0.0.0.0:8081"
+	env["DNSSEEDER_LISTEN"] = "9999"
+
+	got, err := applySettings(fs, getenv)
+	if err != nil || got != cfgFile {
+		t.Fatalf("applySettings: %s %v", got, err)
+	}
+	var tests = []struct {
+		name string
+		want string
+	}{
+		{"p", "5353"},
+		{"w", "0.0.0.0:8081"},
+		{"datadir", "/var/lib/dnsseeder"},
+		{"maxcrawls", "50"},
+		{"v", "true"},
+		{"d", "false"},
+		{"logfile", "/var/log/dnsseeder.log"},
+		{"asmap", ""},
+	}
+	for _, tt := range tests {
+		if v := get(fs, tt.name); v != tt.want {
+			t.Errorf("-%s is %q expected: %q", tt.name, v, tt.want)
+		}
+	}
+
+	// the server config file can come from the environment
+	fs, vals := newFlags()
+	fs.Parse([]string{})
+	env = map[string]string{envConfig: cfgFile}
+	if got, err = applySettings(fs, getenv); err != nil || got != cfgFile || *vals["datadir"] != "/var/lib/dnsseeder" {
+		t.Errorf("config from environment: %s %v datadir: %s", got, err, *vals["datadir"])
+	}
+
+	// no server config file leaves the defaults
+	fs, vals = newFlags()
+	fs.Parse([]string{})
+	env = map[string]string{}
+	if got, err = applySettings(fs, getenv); err != nil || got != "" || *vals["p"] != "8053" {
+		t.Errorf("no config: %s %v port: %s", got, err, *vals["p"])
+	}
+
+	// bad values and unknown fields are errors
+	fs, _ = newFlags()
+	fs.Parse([]string{})
+	env = map[string]string{"DNSSEEDER_MAXCRAWLS": "lots"}
+	if _, err = applySettings(fs, getenv); err == nil {
+		t.Errorf("invalid environment value accepted")
+	}
+	if err = ioutil.WriteFile(cfgFile, []byte(`{"Listn": ":53"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs, _ = newFlags()
+	fs.Parse([]string{"-config", cfgFile})
+	env = map[string]string{}
+	if _, err = applySettings(fs, getenv); err == nil {
+		t.Errorf("unknown server config field accepted")
+	}
+}
+
+func TestServerNetworks(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dnsseeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = os.Mkdir(filepath.Join(dir, "nets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	netA := `{"Name": "NetA", "ID": "0x0709110b", "Port": 8333, "Pver": 70001, "DNSName": "seed.a.test", "TTL": 600}`
+	if err = ioutil.WriteFile(filepath.Join(dir, "nets", "a.json"), []byte(netA), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// network files are relative to the server config file and networks can be inline
+	cfgFile := filepath.Join(dir, "server.json")
+	j := `{"Networks": ["nets/a.json", {"Preset": "bitcoin-main", "DNSName": "seed.b.test"}]}`
+	if err = ioutil.WriteFile(cfgFile, []byte(j), 0644); err != nil {
+		t.Fatal(err)
+	}
+	seeders, order, err := serverNetworks(cfgFile)
+	if err != nil {
+		t.Fatalf("unable to load server networks: %v", err)
+	}
+	if len(order) != 2 || order[0] != "NetA" || order[1] != "BitcoinNet" || seeders["BitcoinNet"].dnsHost != "seed.b.test" {
+		t.Errorf("server networks: %v", order)
+	}
+
+	var bad = []string{
+		`{"Networks": []}`,
+		`{"Networks": ["nets/missing.json"]}`,
+		`{"Networks": [{"Preset": "bitcoin-main", "DNSName": "seed.b.test", "Colour": "blue"}]}`,
+		`{"Networks": ["nets/a.json", "nets/a.json"]}`,
+	}
+	for _, b := range bad {
+		if err = ioutil.WriteFile(cfgFile, []byte(b), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err = serverNetworks(cfgFile); err == nil {
+			t.Errorf("bad server networks accepted: %s", b)
+		}
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+
+	var tests = []struct {
+		listen string
+		dns    string
+		web    string
+	}{
+		{"53", ":53", "127.0.0.1:53"},
+		{"0.0.0.0:8080", "0.0.0.0:8080", "0.0.0.0:8080"},
+		{"[::1]:5353", "[::1]:5353", "[::1]:5353"},
+	}
+	for _, tt := range tests {
+		if got := dnsListenAddr(tt.listen); got != tt.dns {
+			t.Errorf("dns listen %s: %s expected: %s", tt.listen, got, tt.dns)
+		}
+		if got := webListenAddr(tt.listen); got != tt.web {
+			t.Errorf("web listen %s: %s expected: %s", tt.listen, got, tt.web)
+		}
+	}
+}
+
+/*
+
+ */
@@ -0,0 +1,383 @@
+/*
+Package simpeer provides simulated nodes for networks based on Bitcoin technology.
+
+Peers can be served from an in-process Network, which connects the dnsseeder crawler
+to them with net.Pipe so no real network is used, or from a listener on the loopback
+interface. Each peer follows one of a small set of behaviours so the crawler can be
+tested against good, slow, misbehaving and addr flooding nodes.
+*/
+package simpeer
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Behaviour selects how a simulated peer responds to the crawler
+type Behaviour int
+
+const (
+	Good        Behaviour = iota // completes the handshake and returns its address list
+	Slow                         // as Good but waits Delay before every message it sends
+	Misbehaving                  // sends a verack in place of the expected version message
+	AddrFlood                    // as Good but returns FloodSize random addresses
+)
+
+// Codec reads and writes messages for a network that does not use the bitcoin message
+// framing. It has the same methods as the dnsseeder codec
+type Codec interface {
+	ReadMessage(r io.Reader, pver uint32, btcnet wire.BitcoinNet) (wire.Message, []byte, error)
+	WriteMessage(w io.Writer, msg wire.Message, pver uint32, btcnet wire.BitcoinNet) error
+}
+
+// maxAddrPerMsg is the max number of addresses allowed in one addr message
+const maxAddrPerMsg = 1000
+
+// Peer holds the details of one simulated node
+type Peer struct {
+	Behaviour Behaviour           // how the peer responds to the crawler
+	Net       wire.BitcoinNet     // network magic used in the message headers
+	Pver      uint32              // protocol version used to encode messages
+	Version   int32               // protocol version reported in the version message
+	UserAgent string              // user agent reported in the version message
+	Services  wire.ServiceFlag    // services reported in the version message
+	LastBlock int32               // last block reported in the version message
+	Addrs     []*wire.NetAddress  // addresses returned in response to getaddr. If empty no addr message is sent
+	Headers   []*wire.BlockHeader // the chain this peer is on. Headers[0] is the genesis block
+	NoBlocks  bool                // reply to block requests with notfound whatever services we claim
+	Delay     time.Duration       // how long a Slow peer waits before each message
+	FloodSize int                 // number of addresses an AddrFlood peer returns
+	Codec     Codec               // message framing or nil for the bitcoin framing
+}
+
+// NewPeer returns a peer for the network with sensible version details
+func NewPeer(btcnet wire.BitcoinNet, pver uint32, b Behaviour) *Peer {
+	return &Peer{
+		Behaviour: b,
+		Net:       btcnet,
+		Pver:      pver,
+		Version:   int32(pver),
+		UserAgent: "/simpeer:0.1/",
+		Services:  wire.SFNodeNetwork,
+		LastBlock: 100000,
+		Delay:     time.Millisecond * 50,
+		FloodSize: 3000,
+	}
+}
+
+// ServeConn runs the peer side of the protocol on conn until the remote end
+// closes it or sends something we can not handle. The peer answers each request
+// in turn so it can be used over an unbuffered net.Pipe
+func (p *Peer) ServeConn(conn net.Conn) {
+
+	defer conn.Close()
+
+	// first message received should be version
+	msg, err := p.read(conn)
+	if err != nil {
+		return
+	}
+	if _, ok := msg.(*wire.MsgVersion); ok == false {
+		return
+	}
+
+	if p.Behaviour == Misbehaving {
+		p.write(conn, wire.NewMsgVerAck())
+		return
+	}
+
+	me := wire.NewNetAddressIPPort(net.IPv4zero, 0, p.Services)
+	you := wire.NewNetAddressIPPort(net.IPv4zero, 0, 0)
+	msgver := wire.NewMsgVersion(me, you, rand.Uint64(), p.LastBlock)
+	msgver.ProtocolVersion = p.Version
+	msgver.Services = p.Services
+	msgver.UserAgent = p.UserAgent
+	if err = p.write(conn, msgver); err != nil {
+		return
+	}
+
+	// second message received should be verack
+	msg, err = p.read(conn)
+	if err != nil {
+		return
+	}
+	if _, ok := msg.(*wire.MsgVerAck); ok == false {
+		return
+	}
+	if err = p.write(conn, wire.NewMsgVerAck()); err != nil {
+		return
+	}
+
+	for {
+		msg, err = p.read(conn)
+		if err != nil {
+			// unknown messages are decoded as errors so only stop when the
+			// connection has gone
+			if _, ok := err.(*wire.MessageError); ok {
+				continue
+			}
+			return
+		}
+		if err = p.respond(conn, msg); err != nil {
+			return
+		}
+	}
+}
+
+// respond sends the reply, if any, to a message received after the handshake
+func (p *Peer) respond(conn net.Conn, msg wire.Message) error {
+
+	switch msg := msg.(type) {
+	case *wire.MsgGetAddr:
+		addrs := p.Addrs
+		if p.Behaviour == AddrFlood {
+			addrs = randomAddrs(p.FloodSize)
+		}
+		if len(addrs) == 0 {
+			return nil
+		}
+		// like Bitcoin nodes send a one address message first
+		first := wire.NewMsgAddr()
+		first.AddAddress(addrs[0])
+		if err := p.write(conn, first); err != nil {
+			return err
+		}
+		for i := 1; i < len(addrs); i += maxAddrPerMsg {
+			end := i + maxAddrPerMsg
+			if end > len(addrs) {
+				end = len(addrs)
+			}
+			m := wire.NewMsgAddr()
+			if err := m.AddAddresses(addrs[i:end]...); err != nil {
+				return err
+			}
+			if err := p.write(conn, m); err != nil {
+				return err
+			}
+		}
+	case *wire.MsgGetHeaders:
+		return p.write(conn, p.headersAfter(msg.BlockLocatorHashes))
+	case *wire.MsgGetData:
+		return p.write(conn, p.blockFor(msg.InvList))
+	case *wire.MsgPing:
+		return p.write(conn, wire.NewMsgPong(msg.Nonce))
+	}
+	return nil
+}
+
+// headersAfter returns a headers message following the first locator hash found in
+// our chain. Like a real node it starts after the genesis block if none are found
+func (p *Peer) headersAfter(locator []*chainhash.Hash) *wire.MsgHeaders {
+
+	start := 1
+	for _, l := range locator {
+		for i, h := range p.Headers {
+			if h.BlockHash() == *l {
+				start = i + 1
+				break
+			}
+		}
+		if start > 1 {
+			break
+		}
+	}
+
+	msg := wire.NewMsgHeaders()
+	for i := start; i < len(p.Headers) && len(msg.Headers) < wire.MaxBlockHeadersPerMsg; i++ {
+		msg.AddBlockHeader(p.Headers[i])
+	}
+	return msg
+}
+
+// blockFor returns an empty block for the first block request we have a header for
+// or a notfound message
+func (p *Peer) blockFor(invs []*wire.InvVect) wire.Message {
+	if p.NoBlocks == false {
+		for _, iv := range invs {
+			if iv.Type != wire.InvTypeBlock {
+				continue
+			}
+			for _, h := range p.Headers {
+				if h.BlockHash() == iv.Hash {
+					return wire.NewMsgBlock(h)
+				}
+			}
+		}
+	}
+	msg := wire.NewMsgNotFound()
+	for _, iv := range invs {
+		msg.AddInvVect(iv)
+	}
+	return msg
+}
+
+// MakeChain returns base extended by n linked headers using the regression test
+// difficulty. If base is empty the first header is a genesis block. Different
+// nonces produce different chains from the same base.
+func MakeChain(base []*wire.BlockHeader, n int, nonce uint32) []*wire.BlockHeader {
+
+	chain := make([]*wire.BlockHeader, len(base), len(base)+n)
+	copy(chain, base)
+
+	for i := 0; i < n; i++ {
+		var prev chainhash.Hash
+		if len(chain) > 0 {
+			prev = chain[len(chain)-1].BlockHash()
+		}
+		h := wire.NewBlockHeader(1, &prev, &chainhash.Hash{}, 0x207fffff, nonce)
+		h.Timestamp = time.Unix(1296688602+int64(len(chain))*600, 0)
+		chain = append(chain, h)
+	}
+	return chain
+}
+
+// write sends one message to the crawler, waiting first if this is a slow peer
+func (p *Peer) write(conn net.Conn, msg wire.Message) error {
+	if p.Behaviour == Slow {
+		time.Sleep(p.Delay)
+	}
+	if p.Codec != nil {
+		return p.Codec.WriteMessage(conn, msg, p.Pver, p.Net)
+	}
+	return wire.WriteMessage(conn, msg, p.Pver, p.Net)
+}
+
+// read receives one message from the crawler
+func (p *Peer) read(conn net.Conn) (wire.Message, error) {
+	var msg wire.Message
+	var err error
+	if p.Codec != nil {
+		msg, _, err = p.Codec.ReadMessage(conn, p.Pver, p.Net)
+	} else {
+		msg, _, err = wire.ReadMessage(conn, p.Pver, p.Net)
+	}
+	return msg, err
+}
+
+// Listen serves the peer on a random port on the loopback interface until
+// the returned listener is closed
+func (p *Peer) Listen() (net.Listener, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go p.ServeConn(conn)
+		}
+	}()
+	return l, nil
+}
+
+// randomAddrs returns n recently seen addresses in the 1.0.0.0/8 to 99.0.0.0/8 range
+func randomAddrs(n int) []*wire.NetAddress {
+	addrs := make([]*wire.NetAddress, n)
+	for i := range addrs {
+		ip := net.IPv4(byte(1+rand.Intn(99)), byte(rand.Intn(256)), byte(rand.Intn(256)), byte(1+rand.Intn(254)))
+		addrs[i] = wire.NewNetAddressIPPort(ip, uint16(1024+rand.Intn(60000)), wire.SFNodeNetwork)
+	}
+	return addrs
+}
+
+// Network is an in-process network of simulated peers and host names. It provides
+// the Dial and LookupHost methods used by the dnsseeder to reach the outside world
+type Network struct {
+	mtx   sync.RWMutex
+	peers map[string]*Peer    // peers keyed by the ip:port they listen on
+	hosts map[string][]string // ip addresses returned for each host name
+	dials map[string]int      // number of connections made to each address
+}
+
+// NewNetwork returns an empty simulated network
+func NewNetwork() *Network {
+	return &Network{
+		peers: make(map[string]*Peer),
+		hosts: make(map[string][]string),
+		dials: make(map[string]int),
+	}
+}
+
+// AddPeer makes a peer reachable at the ip address and port
+func (n *Network) AddPeer(ip string, port uint16, p *Peer) {
+	n.mtx.Lock()
+	n.peers[net.JoinHostPort(ip, strconv.Itoa(int(port)))] = p
+	n.mtx.Unlock()
+}
+
+// AddHost sets the ip addresses returned when host is looked up
+func (n *Network) AddHost(host string, ips ...string) {
+	n.mtx.Lock()
+	n.hosts[host] = ips
+	n.mtx.Unlock()
+}
+
+// Dials returns the number of connections that have been made to an address
+func (n *Network) Dials(address string) int {
+	n.mtx.RLock()
+	defer n.mtx.RUnlock()
+	return n.dials[address]
+}
+
+// Dial connects to the simulated peer at address. If there is no peer there
+// the connection is refused
+func (n *Network) Dial(network, address string) (net.Conn, error) {
+
+	n.mtx.Lock()
+	n.dials[address]++
+	p, ok := n.peers[address]
+	n.mtx.Unlock()
+
+	raddr, err := net.ResolveTCPAddr("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if ok == false {
+		return nil, &net.OpError{Op: "dial", Net: network, Addr: raddr, Err: syscall.ECONNREFUSED}
+	}
+
+	local, remote := net.Pipe()
+	go p.ServeConn(remote)
+
+	return &pipeConn{
+		Conn:  local,
+		laddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1024 + rand.Intn(60000)},
+		raddr: raddr,
+	}, nil
+}
+
+// LookupHost returns the ip addresses added for host
+func (n *Network) LookupHost(host string) ([]string, error) {
+	n.mtx.RLock()
+	defer n.mtx.RUnlock()
+
+	ips, ok := n.hosts[host]
+	if ok == false {
+		return nil, &net.DNSError{Err: "no such host", Name: host}
+	}
+	return ips, nil
+}
+
+// pipeConn is one end of a net.Pipe that reports tcp addresses like a real connection
+type pipeConn struct {
+	net.Conn
+	laddr *net.TCPAddr
+	raddr *net.TCPAddr
+}
+
+// LocalAddr returns the fake local tcp address
+func (c *pipeConn) LocalAddr() net.Addr { return c.laddr }
+
+// RemoteAddr returns the tcp address that was dialled
+func (c *pipeConn) RemoteAddr() net.Addr { return c.raddr }
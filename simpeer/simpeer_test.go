@@ -0,0 +1,125 @@
+package simpeer
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestDial(t *testing.T) {
+
+	sn := NewNetwork()
+	sn.AddPeer("1.2.3.4", 8333, NewPeer(wire.TestNet3, 70001, Good))
+
+	conn, err := sn.Dial("tcp", "1.2.3.4:8333")
+	if err != nil {
+		t.Fatalf("unable to dial peer: %v", err)
+	}
+	defer conn.Close()
+
+	// the crawler reads the tcp addresses of the connection like a real one
+	raddr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if ok == false || raddr.IP.Equal(net.IPv4(1, 2, 3, 4)) == false || raddr.Port != 8333 {
+		t.Errorf("remote address: %v expected: 1.2.3.4:8333", conn.RemoteAddr())
+	}
+	laddr, ok := conn.LocalAddr().(*net.TCPAddr)
+	if ok == false || laddr.IP.IsLoopback() == false || laddr.Port < 1024 {
+		t.Errorf("local address: %v expected a loopback tcp address", conn.LocalAddr())
+	}
+
+	// an address with no peer refuses the connection and the dial is still counted
+	_, err = sn.Dial("tcp", "5.6.7.8:8333")
+	if oe, ok := err.(*net.OpError); ok == false || oe.Err != syscall.ECONNREFUSED {
+		t.Errorf("dial with no peer error: %v expected: connection refused", err)
+	}
+	if sn.Dials("1.2.3.4:8333") != 1 || sn.Dials("5.6.7.8:8333") != 1 {
+		t.Errorf("dials: %v %v expected: 1 1", sn.Dials("1.2.3.4:8333"), sn.Dials("5.6.7.8:8333"))
+	}
+	if _, err = sn.Dial("tcp", "no-port"); err == nil {
+		t.Errorf("dial of an invalid address did not fail")
+	}
+
+	sn.AddHost("seed.example.com", "1.2.3.4")
+	if ips, err := sn.LookupHost("seed.example.com"); err != nil || len(ips) != 1 || ips[0] != "1.2.3.4" {
+		t.Errorf("lookup: %v err: %v", ips, err)
+	}
+	if _, err = sn.LookupHost("unknown.example.com"); err == nil {
+		t.Errorf("lookup of an unknown host did not fail")
+	}
+}
+
+func TestHandshake(t *testing.T) {
+
+	p := NewPeer(wire.TestNet3, 70001, Good)
+	p.LastBlock = 123
+	p.Addrs = []*wire.NetAddress{
+		wire.NewNetAddressIPPort(net.IPv4(5, 6, 7, 8), 8333, wire.SFNodeNetwork),
+		wire.NewNetAddressIPPort(net.IPv4(5, 6, 7, 9), 8333, wire.SFNodeNetwork),
+		wire.NewNetAddressIPPort(net.IPv4(5, 6, 7, 10), 8333, wire.SFNodeNetwork),
+	}
+	sn := NewNetwork()
+	sn.AddPeer("1.2.3.4", 8333, p)
+
+	conn, err := sn.Dial("tcp", "1.2.3.4:8333")
+	if err != nil {
+		t.Fatalf("unable to dial peer: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	send := func(msg wire.Message) {
+		if err := wire.WriteMessage(conn, msg, p.Pver, p.Net); err != nil {
+			t.Fatalf("unable to send %s: %v", msg.Command(), err)
+		}
+	}
+	recv := func() wire.Message {
+		msg, _, err := wire.ReadMessage(conn, p.Pver, p.Net)
+		if err != nil {
+			t.Fatalf("unable to read message: %v", err)
+		}
+		return msg
+	}
+
+	me := wire.NewNetAddressIPPort(net.IPv4zero, 0, 0)
+	send(wire.NewMsgVersion(me, me, 1, 0))
+
+	// every reply uses the bitcoin framing with the network magic
+	ver, ok := recv().(*wire.MsgVersion)
+	if ok == false {
+		t.Fatalf("peer did not answer with a version message")
+	}
+	if ver.ProtocolVersion != 70001 || ver.UserAgent != "/simpeer:0.1/" || ver.LastBlock != 123 || ver.Services != wire.SFNodeNetwork {
+		t.Errorf("version: %v %s %v %v", ver.ProtocolVersion, ver.UserAgent, ver.LastBlock, ver.Services)
+	}
+	send(wire.NewMsgVerAck())
+	if _, ok = recv().(*wire.MsgVerAck); ok == false {
+		t.Fatalf("peer did not answer with a verack message")
+	}
+
+	// like a Bitcoin node the first addr message holds one address
+	send(wire.NewMsgGetAddr())
+	first, ok := recv().(*wire.MsgAddr)
+	if ok == false || len(first.AddrList) != 1 {
+		t.Fatalf("first reply to getaddr is not a one address addr message")
+	}
+	rest, ok := recv().(*wire.MsgAddr)
+	if ok == false || len(rest.AddrList) != 2 {
+		t.Errorf("second reply to getaddr is not an addr message with the other 2 addresses")
+	}
+
+	// a misbehaving peer sends a verack in place of its version
+	sn.AddPeer("1.2.3.5", 8333, NewPeer(wire.TestNet3, 70001, Misbehaving))
+	bad, err := sn.Dial("tcp", "1.2.3.5:8333")
+	if err != nil {
+		t.Fatalf("unable to dial peer: %v", err)
+	}
+	defer bad.Close()
+	bad.SetDeadline(time.Now().Add(5 * time.Second))
+	wire.WriteMessage(bad, wire.NewMsgVersion(me, me, 1, 0), p.Pver, p.Net)
+	if msg, _, err := wire.ReadMessage(bad, p.Pver, p.Net); err != nil || msg.Command() != wire.CmdVerAck {
+		t.Errorf("misbehaving peer sent: %v err: %v expected: verack", msg, err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"syscall"
+	"time"
+)
+
+// ipv6TestAddr is used to check for an ipv6 route. No packets are sent to it
+const ipv6TestAddr = "[2001:4860:4860::8888]:53"
+
+// dialer opens connections to remote nodes. A *net.Dialer satisfies this and tests
+// can replace it with an in-process network so no real connections are made
+type dialer interface {
+	Dial(network, address string) (net.Conn, error)
+}
+
+// resolver looks up the ip addresses for a host name. It is used to query
+// the other seeders for a network when bootstrapping
+type resolver interface {
+	LookupHost(host string) ([]string, error)
+}
+
+// netResolver uses the system resolver
+type netResolver struct{}
+
+// LookupHost returns the ip addresses for host using the system resolver
+func (netResolver) LookupHost(host string) ([]string, error) {
+	return net.LookupHost(host)
+}
+
+// familyDialer connects from a different local address depending on the
+// address family of the remote node
+type familyDialer struct {
+	v4 *net.Dialer
+	v6 *net.Dialer
+}
+
+// Dial connects to address using the dialer for its address family
+func (d *familyDialer) Dial(network, address string) (net.Conn, error) {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			return d.v6.Dial(network, address)
+		}
+	}
+	return d.v4.Dial(network, address)
+}
+
+// newNetDialer returns the dialer used to connect to real nodes. If a bind
+// address is nil the kernel picks the local address for that family
+func newNetDialer(bind4, bind6 net.IP) dialer {
+	d := &familyDialer{
+		v4: &net.Dialer{Timeout: time.Second * 10},
+		v6: &net.Dialer{Timeout: time.Second * 10},
+	}
+	if bind4 != nil {
+		d.v4.LocalAddr = &net.TCPAddr{IP: bind4}
+	}
+	if bind6 != nil {
+		d.v6.LocalAddr = &net.TCPAddr{IP: bind6}
+	}
+	return d
+}
+
+// checkIPv6 looks for a route to the ipv6 internet from our bind address and
+// records if ipv6 is down. Connecting a udp socket sends no packets but fails
+// if there is no route
+func (s *dnsseeder) checkIPv6() {
+
+	if s.disableIPv6 {
+		return
+	}
+
+	d := net.Dialer{}
+	if s.bindIPv6 != nil {
+		d.LocalAddr = &net.UDPAddr{IP: s.bindIPv6}
+	}
+	conn, err := d.Dial("udp6", ipv6TestAddr)
+	if conn != nil {
+		conn.Close()
+	}
+
+	s.mtx.Lock()
+	changed := s.ipv6Down != (err != nil)
+	s.ipv6Down = err != nil
+	s.mtx.Unlock()
+
+	if changed {
+		if err != nil {
+			log.Printf("%s: no ipv6 connectivity. ipv6 nodes will not be crawled - %v\n", s.name, err)
+		} else {
+			log.Printf("%s: ipv6 connectivity available. ipv6 nodes will be crawled\n", s.name)
+		}
+	}
+}
+
+// families returns a description of the address families this seeder is crawling
+func (s *dnsseeder) families() string {
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	v4, v6 := "crawling", "crawling"
+	if s.disableIPv4 {
+		v4 = "disabled"
+	}
+	if s.disableIPv6 {
+		v6 = "disabled"
+	} else if s.ipv6Down {
+		v6 = "no route"
+	}
+	return "IPv4: " + v4 + " IPv6: " + v6
+}
+
+// isNoRoute returns true if a connection failed because we have no route to
+// the remote network rather than a problem with the remote node
+func isNoRoute(err error) bool {
+	return errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EADDRNOTAVAIL)
+}
+
+/*
+
+ */
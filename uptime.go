@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const minRetry = 1000 // seconds assumed since the last try for a node we have never tried
+
+const (
+	// windows for the node reliability stats
+	stat2H         = iota // 2 hours
+	stat8H                // 8 hours
+	stat1D                // 1 day
+	stat7D                // 7 days
+	stat30D               // 30 days
+	maxStatWindows        // used to allocate arrays
+)
+
+// statTau is the time constant in seconds for each stats window
+var statTau = [maxStatWindows]float64{3600 * 2, 3600 * 8, 3600 * 24, 3600 * 24 * 7, 3600 * 24 * 30}
+
+// addrStat is an exponentially decayed record of the crawl results for a node over one
+// window. It follows the stats kept by sipa's bitcoin-seeder
+type addrStat struct {
+	Weight      float64
+	Count       float64
+	Reliability float64
+}
+
+// update decays the stats by the seconds since the last crawl and adds the result of this crawl
+func (a *addrStat) update(good bool, age, tau float64) {
+	f := math.Exp(-age / tau)
+	a.Reliability = a.Reliability * f
+	if good {
+		a.Reliability += 1.0 - f
+	}
+	a.Count = a.Count*f + 1
+	a.Weight = a.Weight*f + (1.0 - f)
+}
+
+// updateStats adds the result of a crawl to all the stats windows of a node.
+// It must be called before lastTry is updated for this crawl
+func (nd *node) updateStats(good bool) {
+	age := float64(minRetry)
+	if nd.lastTry.IsZero() == false {
+		age = time.Since(nd.lastTry).Seconds()
+	}
+	for i := range nd.stats {
+		nd.stats[i].update(good, age, statTau[i])
+	}
+}
+
+// uptime returns the reliability of the node over a stats window as a percentage
+func (nd *node) uptime(window int) float64 {
+	return 100.0 * nd.stats[window].Reliability
+}
+
+// score returns the value used to rank nodes for dns answers. It favours nodes that
+// have been reliable over the shorter windows while still counting their history
+func (nd *node) score() float64 {
+	return 0.3*nd.stats[stat2H].Reliability +
+		0.3*nd.stats[stat8H].Reliability +
+		0.2*nd.stats[stat1D].Reliability +
+		0.1*nd.stats[stat7D].Reliability +
+		0.1*nd.stats[stat30D].Reliability
+}
+
+// rankNodes sorts the nodes in a random order weighted by their score so the most
+// reliable nodes are usually first while every node still gets a chance to be served
+func rankNodes(nds []*node) {
+
+	// weighted random sampling where each node gets a key of u^(1/w)
+	keys := make(map[*node]float64, len(nds))
+	for _, nd := range nds {
+		keys[nd] = math.Pow(rand.Float64(), 1.0/(nd.score()+0.01))
+	}
+
+	sort.Slice(nds, func(i, j int) bool { return keys[nds[i]] > keys[nds[j]] })
+}
+
+/*
+
+ */
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAddrStat(t *testing.T) {
+
+	var a addrStat
+
+	// a node that is always up converges on 100% reliability
+	for i := 0; i < 50; i++ {
+		a.update(true, 3600, statTau[stat2H])
+	}
+	if math.Abs(a.Reliability-1.0) > 0.0001 {
+		t.Errorf("always up reliability: %v expected: 1", a.Reliability)
+	}
+
+	// one failure an hour later is decayed by exp(-age/tau)
+	a.update(false, 3600, statTau[stat2H])
+	if expected := math.Exp(-0.5); math.Abs(a.Reliability-expected) > 0.0001 {
+		t.Errorf("reliability after failure: %v expected: %v", a.Reliability, expected)
+	}
+
+	// the longer windows forget failures more slowly
+	nd := &node{lastTry: time.Now().Add(-time.Hour)}
+	nd.updateStats(true)
+	for w := stat2H; w < stat30D; w++ {
+		if nd.uptime(w) <= nd.uptime(w+1) {
+			t.Errorf("window %v uptime: %v should be above window %v uptime: %v", w, nd.uptime(w), w+1, nd.uptime(w+1))
+		}
+	}
+}
+
+func TestRankNodes(t *testing.T) {
+
+	good := &node{}
+	for i := range good.stats {
+		good.stats[i].Reliability = 1.0
+	}
+	bad := &node{}
+
+	// the reliable node should nearly always be ranked first
+	first := 0
+	for i := 0; i < 1000; i++ {
+		nds := []*node{bad, good}
+		rankNodes(nds)
+		if nds[0] == good {
+			first++
+		}
+	}
+	if first < 900 {
+		t.Errorf("reliable node ranked first %v times out of 1000", first)
+	}
+}
+
+/*
+
+ */